@@ -6,10 +6,21 @@ import (
 	"github.com/namsral/flag"
 )
 
+var configFile = flag.String("config", "", "A JSON file with options of owners and repositories")
+var baseURL = flag.String("baseURL", "", "A public URL of the server, ex. https://my-domain.com, used for Changelogs of Release, which are not advertised if empty")
 var httpAddr = flag.String("httpAddr", ":5000", "HTTP Address to listen to")
 var requestCacheExpiration = flag.Duration("requestCache", 24*time.Hour, "Request cache expiration timeout")
 var packageLruCache = flag.Int("packageLruCache", 10000, "Number of packages stored in memory")
-var suites = flag.String("suites", "stretch,jessie,xenial,bionic", "A list of supported suites")
+var snapshotLruCache = flag.Int("snapshotLruCache", 1000, "Number of rendered repositories stored in memory")
+var snapshotCacheSize = flag.Int("snapshotCacheSize", 1024, "Size in megabytes of rendered repositories stored in memory")
+var byHashRetention = flag.Duration("byHashRetention", 48*time.Hour, "How long indices of previous generations are available by hash")
+var byHashCacheSize = flag.Int("byHashCacheSize", 256, "Size in megabytes of indices of previous generations stored in memory")
+var pdiffHistory = flag.Int("pdiffHistory", 10, "Number of previous generations of Packages for which patches are published, 0 disables")
+var releaseCacheControl = flag.String("releaseCacheControl", "public, max-age=60", "Cache-Control of Release, InRelease and indices")
+var byHashCacheControl = flag.String("byHashCacheControl", "public, max-age=31536000, immutable", "Cache-Control of indices requested by hash")
+var suites = flag.String("suites", "stretch,jessie,xenial,bionic", "A list of suites that are always published, others are discovered from packages")
 var architectures = flag.String("architectures", "arm64,armhf,amd64", "A list of supported architectures")
+var compressors = flag.String("compressors", "gz,xz", "A list of compressors used for indices: gz, xz, bz2, zstd, or empty for uncompressed only")
+var disableLintRules = flag.String("disableLintRules", "", "A list of package lint rules to disable")
 
 var parseDeb = flag.String("parseDeb", "", "Try to parse a debian archive")
@@ -1,6 +1,6 @@
 module github.com/ayufan/debian-repository
 
-go 1.17
+go 1.22
 
 require (
 	github.com/Tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
@@ -8,6 +8,7 @@ require (
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da
 	github.com/google/go-github v17.0.0+incompatible
 	github.com/gorilla/mux v1.8.0
+	github.com/klauspost/compress v1.18.0
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/stapelberg/godebiancontrol v0.0.0-20180408134423-8c93e189186a
 	github.com/ulikunitz/xz v0.5.10
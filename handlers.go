@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -35,11 +38,23 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	schema := r.Header.Get("X-Forwarded-Proto")
-	if schema == "" {
-		schema = "http"
+	options := repositoryConfig.Options(vars["owner"], vars["repo"])
+
+	// suites are discovered from packages
+	repository, err := getRepository(w, r)
+	if http_helpers.HandleError(w, err) {
+		return
+	}
+	suites := repository.Suites()
+
+	// releases and pre-releases are first
+	components := options.ComponentNames()
+	var debugComponents []string
+	for _, component := range components {
+		debugComponents = append(debugComponents, component+"-debug")
 	}
-	url := schema + "://" + r.Host + strings.TrimSuffix(r.URL.String(), "/")
+
+	url := getBaseURL(r) + strings.TrimSuffix(r.URL.String(), "/")
 
 	fmt.Fprintln(w, "<h2>Welcome to automated Debian Repository made on top of GitHub Releases</h2>")
 
@@ -58,36 +73,49 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "<code>$ curl -fsSL "+url+"/archive.key | sudo apt-key add -</code>")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "<h4>2. Add stable repository:</h4>")
-	for _, suite := range deb.Suites {
+	for _, suite := range suites {
 		fmt.Fprintln(w, `<code>$ sudo add-apt-repository "deb `+url+` `+suite+` releases"</code><br>`)
 	}
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "<h4>3. (optionally) Add pre-release repository:</h4>")
-	for _, suite := range deb.Suites {
+	for _, suite := range suites {
 		fmt.Fprintln(w, `<code>$ sudo add-apt-repository "deb `+url+` `+suite+` releases pre-releases"</code><br>`)
 	}
+	for _, component := range components[2:] {
+		for _, suite := range suites {
+			fmt.Fprintln(w, `<code>$ sudo add-apt-repository "deb `+url+` `+suite+` `+component+`"</code><br>`)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "<h4>4. (optionally) Add debug symbols repository:</h4>")
+	for _, suite := range suites {
+		fmt.Fprintln(w, `<code>$ sudo add-apt-repository "deb `+url+` `+suite+` `+strings.Join(debugComponents, " ")+`"</code><br>`)
+	}
 	fmt.Fprintln(w)
-	fmt.Fprintln(w, "<h4>4. Update apt:</h4>")
+	fmt.Fprintln(w, "<h4>5. Update apt:</h4>")
 	fmt.Fprintln(w, `<code>$ sudo apt-get update</code>`)
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "<h4>You can view the status of all packages at:</h4>")
-	fmt.Fprintf(w, `<a href=%q>%s</a><br>`, url+"/releases", url+"/releases")
-	fmt.Fprintf(w, `<a href=%q>%s</a><br>`, url+"/pre-releases", url+"/pre-releases")
+	for _, component := range components {
+		fmt.Fprintf(w, `<a href=%q>%s</a><br>`, url+"/"+component, url+"/"+component)
+	}
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "<h4>You can view all packages at:</h4>")
-	for _, suite := range deb.Suites {
+	for _, suite := range suites {
 		fmt.Fprintf(w, `<a href=%q>%s</a><br>`, url+"/dists/"+suite+"/InRelease", url+"/dists/"+suite+"/InRelease")
 	}
 	fmt.Fprintln(w)
 }
 
 func distributionIndexHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
 	w.Header().Set("Content-Type", "text/plain")
 
 	fmt.Fprintln(w, "List of packages:")
 
 	err := enumeratePackages(w, r, func(ghPackage github_client.Package) error {
-		p, err := packagesCache.Get(ghPackage)
+		p, err := getPackage(vars["owner"], ghPackage)
 		fmt.Fprintln(w, "Package:", *ghPackage.Release.TagName, "/", *ghPackage.Asset.Name)
 		fmt.Fprintln(w, "\tIsPrerelease:", *ghPackage.Release.Prerelease)
 		fmt.Fprintln(w, "\tStatus:", err)
@@ -96,8 +124,12 @@ func distributionIndexHandler(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprintln(w, "\tDownloadURL:", p.DownloadURL)
 			fmt.Fprintln(w, "\tSize:", p.FileSize)
 			fmt.Fprintln(w, "\tUpdatedAt:", p.UpdatedAt)
-			fmt.Fprintln(w, "\tSuite:", p.Suite)
+			fmt.Fprintln(w, "\tSuites:", strings.Join(p.Suites, ", "))
+			fmt.Fprintln(w, "\tSuiteReason:", p.SuiteReason)
 			fmt.Fprintln(w, "\tComponent:", p.Component)
+			for _, finding := range p.Findings {
+				fmt.Fprintln(w, "\tFinding:", finding)
+			}
 		}
 		fmt.Fprintln(w)
 		return nil
@@ -107,6 +139,99 @@ func distributionIndexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func changelogHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	changelogPath := strings.Join([]string{vars["component"], vars["prefix"], vars["source"], vars["file"]}, "/")
+	changelogPath = strings.TrimSuffix(changelogPath, "_changelog")
+
+	var changelog []byte
+
+	err := enumeratePackages(w, r, func(ghPackage github_client.Package) error {
+		p, err := getPackage(vars["owner"], ghPackage)
+		if err == nil && changelog == nil && p.Type != deb.SourcePackage && p.ChangelogPath() == changelogPath {
+			changelog = p.Changelog()
+		}
+		return nil
+	})
+	if http_helpers.HandleError(w, err) {
+		return
+	}
+
+	if changelog == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(changelog)
+}
+
+func findPackage(w http.ResponseWriter, r *http.Request) (*deb.Package, error) {
+	vars := mux.Vars(r)
+
+	var found *deb.Package
+
+	err := enumeratePackages(w, r, func(ghPackage github_client.Package) error {
+		p, err := getPackage(vars["owner"], ghPackage)
+		if err != nil || found != nil {
+			return nil
+		}
+		if p.TagName == vars["tag_name"] && p.FileName == vars["file_name"] {
+			found = p
+		}
+		return nil
+	})
+	return found, err
+}
+
+// escapePath escapes each segment of path, names of members come from uploaded packages
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for idx, segment := range segments {
+		segments[idx] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func inspectHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := findPackage(w, r)
+	if http_helpers.HandleError(w, err) {
+		return
+	}
+	if p == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	if vars["member"] != "" {
+		data, ok := p.ControlFiles[vars["member"]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+
+	fmt.Fprintf(w, "<h2>%s</h2>\n", html.EscapeString(p.FileName))
+	fmt.Fprintln(w, "Files of control.tar:")
+	fmt.Fprintln(w, "<ul>")
+	for _, member := range p.ControlFileNames() {
+		memberURL := strings.TrimSuffix(r.URL.EscapedPath(), "/") + "/" + escapePath(member)
+		fmt.Fprintf(w, `<li><a href="%s">%s</a> (%d bytes)</li>`,
+			html.EscapeString(memberURL), html.EscapeString(member), len(p.ControlFiles[member]))
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "</ul>")
+}
+
 func archiveKeyHandler(w http.ResponseWriter, r *http.Request) {
 	err := signingKey.WriteKey(w)
 	if http_helpers.HandleError(w, err) {
@@ -115,22 +240,43 @@ func archiveKeyHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func fileHandler(w http.ResponseWriter, r *http.Request) {
-	repository, err := getRepository(w, r)
+	repository, err := getSnapshot(w, r)
 	if http_helpers.HandleError(w, err) {
 		return
 	}
 
 	vars := mux.Vars(r)
 
-	files := repository.AllFiles()
-	file := files[vars["file"]]
+	file, err := repository.File(vars["file"])
+	if http_helpers.HandleError(w, err) {
+		return
+	}
 	if file == nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	err = file.Writer(w)
-	http_helpers.HandleError(w, err)
+	hash, err := file.Hash()
+	if http_helpers.HandleError(w, err) {
+		return
+	}
+
+	// Release changes also when Valid-Until is moved forward,
+	// files by hash never change
+	cacheControl := *releaseCacheControl
+	modTime := repository.ModifiedAt()
+	if deb.IsByHashPath(vars["file"]) {
+		cacheControl = *byHashCacheControl
+		modTime = repository.UpdatedAt()
+	}
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.Header().Set("Content-Type", http_helpers.ContentType(vars["file"]))
+	w.Header().Set("ETag", `"`+hash.Hex("SHA256")+`"`)
+
+	// handles conditional requests, HEAD and ranges
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(hash.Bytes()))
 }
 
 var httpProxy = httputil.ReverseProxy{
@@ -179,4 +325,5 @@ func clearHandler(w http.ResponseWriter, r *http.Request) {
 
 	githubAPI.Flush()
 	packagesCache.Clear()
+	snapshotsCache.Clear()
 }
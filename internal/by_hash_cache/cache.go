@@ -0,0 +1,110 @@
+package by_hash_cache
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	keys      []string
+	data      []byte
+	expiresAt time.Time
+}
+
+// Cache keeps the content of indices from previous generations,
+// so they can still be fetched by hash after InRelease changes,
+// the oldest are evicted when over the size
+type Cache struct {
+	entries   *list.List
+	keys      map[string]*list.Element
+	lock      sync.Mutex
+	size      int64
+	maxSize   int64
+	retention time.Duration
+}
+
+func (c *Cache) remove(element *list.Element) {
+	entry := element.Value.(*entry)
+	for _, key := range entry.keys {
+		if c.keys[key] == element {
+			delete(c.keys, key)
+		}
+	}
+	c.entries.Remove(element)
+	c.size -= int64(len(entry.data))
+}
+
+// evict removes expired entries, and the oldest ones when over the size
+func (c *Cache) evict(now time.Time) {
+	for element := c.entries.Front(); element != nil; element = c.entries.Front() {
+		if c.size <= c.maxSize && element.Value.(*entry).expiresAt.After(now) {
+			break
+		}
+		c.remove(element)
+	}
+}
+
+// Add stores data under all keys, the same data is counted once
+func (c *Cache) Add(keys []string, data []byte) {
+	if c == nil {
+		return
+	}
+
+	if int64(len(data)) > c.maxSize {
+		log.Println("By-hash file of", len(data), "bytes is over the cache size")
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range keys {
+		if element := c.keys[key]; element != nil {
+			c.remove(element)
+		}
+	}
+
+	now := time.Now()
+	element := c.entries.PushBack(&entry{
+		keys:      keys,
+		data:      data,
+		expiresAt: now.Add(c.retention),
+	})
+	for _, key := range keys {
+		c.keys[key] = element
+	}
+	c.size += int64(len(data))
+
+	c.evict(now)
+}
+
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element := c.keys[key]
+	if element == nil {
+		return nil, false
+	}
+
+	entry := element.Value.(*entry)
+	if !entry.expiresAt.After(time.Now()) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func New(retention time.Duration, maxSize int64) *Cache {
+	return &Cache{
+		entries:   list.New(),
+		keys:      make(map[string]*list.Element),
+		maxSize:   maxSize,
+		retention: retention,
+	}
+}
@@ -0,0 +1,89 @@
+package by_hash_cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictOldest(t *testing.T) {
+	c := New(time.Hour, 10)
+
+	c.Add([]string{"a"}, []byte("1111"))
+	c.Add([]string{"b"}, []byte("2222"))
+	c.Add([]string{"c"}, []byte("3333"))
+
+	if _, found := c.Get("a"); found {
+		t.Error("the oldest entry is not evicted")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("%s is evicted", key)
+		}
+	}
+	if c.size != 8 {
+		t.Errorf("size = %d, expected 8", c.size)
+	}
+}
+
+func TestSharedKeys(t *testing.T) {
+	c := New(time.Hour, 10)
+
+	// empty indices of a directory have the same by-hash paths
+	keys := []string{"dir/by-hash/SHA256/e3b0", "dir/by-hash/MD5Sum/d41d"}
+	c.Add(keys, []byte("empty"))
+	c.Add(keys, []byte("empty"))
+
+	if c.size != 5 || c.entries.Len() != 1 {
+		t.Errorf("the same content is stored %d times, of %d bytes", c.entries.Len(), c.size)
+	}
+	for _, key := range keys {
+		if data, found := c.Get(key); !found || string(data) != "empty" {
+			t.Errorf("%s = %q, %v", key, data, found)
+		}
+	}
+
+	// all keys of evicted content are removed
+	c.Add([]string{"other"}, []byte("0123456789"))
+	for _, key := range keys {
+		if _, found := c.Get(key); found {
+			t.Errorf("%s is not evicted", key)
+		}
+	}
+	if len(c.keys) != 1 || c.size != 10 {
+		t.Errorf("keys = %v, size = %d, expected only other", c.keys, c.size)
+	}
+}
+
+func TestOverSize(t *testing.T) {
+	c := New(time.Hour, 4)
+
+	c.Add([]string{"a"}, []byte("1111"))
+	c.Add([]string{"b"}, []byte("22222"))
+
+	if _, found := c.Get("b"); found {
+		t.Error("entry over the size is stored")
+	}
+	if _, found := c.Get("a"); !found {
+		t.Error("entry is evicted by one over the size")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	c := New(-time.Second, 10)
+
+	c.Add([]string{"a"}, []byte("1111"))
+	if _, found := c.Get("a"); found {
+		t.Error("expired entry is returned")
+	}
+
+	c.retention = time.Hour
+	c.Add([]string{"b"}, []byte("2222"))
+	c.Add([]string{"c"}, []byte("3333"))
+
+	if c.entries.Len() != 2 || c.size != 8 {
+		t.Errorf("%d entries of %d bytes, expected 2 of 8 bytes", c.entries.Len(), c.size)
+	}
+	if _, found := c.Get("b"); !found {
+		t.Error("entry is evicted before its retention")
+	}
+}
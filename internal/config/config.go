@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ayufan/debian-repository/internal/deb"
+)
+
+// Config holds options of deb.Options:
+// the top-level are global, and can be overwritten by owners and their repositories:
+//
+//	{
+//	  "release": {"label": "My Label"},
+//	  "owners": {
+//	    "my-org": {
+//	      "release": {"valid_for": "168h"},
+//	      "repositories": {
+//	        "my-repo": {"release": {"suites": {"bionic": {"not_automatic": true}}}}
+//	      }
+//	    }
+//	  }
+//	}
+type Config struct {
+	global *deb.Options
+	owners map[string]*ownerOptions
+}
+
+// ownerOptions are resolved once, when the config is parsed
+type ownerOptions struct {
+	options      *deb.Options
+	repositories map[string]*deb.Options
+}
+
+type ownerConfig struct {
+	Settings     json.RawMessage            `json:"-"`
+	Repositories map[string]json.RawMessage `json:"repositories"`
+}
+
+// unmarshal applies a layer of options, unknown keys are errors
+func unmarshal(data json.RawMessage, options *deb.Options) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(options)
+}
+
+// withoutKey returns options without a key of nested configs
+func withoutKey(data json.RawMessage, key string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(fields, key)
+	return json.Marshal(fields)
+}
+
+// Options returns options of the repository, they are shared and must not be modified
+func (c *Config) Options(owner, repo string) *deb.Options {
+	if c == nil {
+		return deb.DefaultOptions()
+	}
+
+	ownerOptions := c.owners[owner]
+	if ownerOptions == nil {
+		return c.global
+	}
+
+	repoOptions := ownerOptions.repositories[repo]
+	if repoOptions == nil {
+		return ownerOptions.options
+	}
+	return repoOptions
+}
+
+// resolve applies layers of options on top of each other
+func resolve(layers ...json.RawMessage) (*deb.Options, error) {
+	options := deb.DefaultOptions()
+	for _, layer := range layers {
+		err := unmarshal(layer, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+func Parse(data []byte) (*Config, error) {
+	var config struct {
+		Owners map[string]json.RawMessage `json:"owners"`
+	}
+
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	global, err := withoutKey(data, "owners")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{
+		owners: make(map[string]*ownerOptions),
+	}
+
+	c.global, err = resolve(global)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+
+	for owner, settings := range config.Owners {
+		ownerConfig := &ownerConfig{}
+		err = json.Unmarshal(settings, ownerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("config of %s: %v", owner, err)
+		}
+
+		ownerConfig.Settings, err = withoutKey(settings, "repositories")
+		if err != nil {
+			return nil, fmt.Errorf("config of %s: %v", owner, err)
+		}
+
+		ownerOptions := &ownerOptions{
+			repositories: make(map[string]*deb.Options),
+		}
+		ownerOptions.options, err = resolve(global, ownerConfig.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("config of %s: %v", owner, err)
+		}
+
+		for repo, repoConfig := range ownerConfig.Repositories {
+			ownerOptions.repositories[repo], err = resolve(global, ownerConfig.Settings, repoConfig)
+			if err != nil {
+				return nil, fmt.Errorf("config of %s/%s: %v", owner, repo, err)
+			}
+		}
+		c.owners[owner] = ownerOptions
+	}
+	return c, nil
+}
+
+func Load(fileName string) (*Config, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+const testConfig = `{
+  "release": {"label": "Global", "origin": "Global Origin", "valid_for": "72h"},
+  "owners": {
+    "my-org": {
+      "release": {"label": "My Org", "valid_for": "168h"},
+      "repositories": {
+        "my-repo": {"release": {"label": "My Repo", "suites": {"bionic": {"not_automatic": true}}}}
+      }
+    }
+  }
+}`
+
+func TestOptionsOverrides(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		owner, repo  string
+		label        string
+		origin       string
+		validFor     time.Duration
+		notAutomatic bool
+	}{
+		{"other-org", "", "Global", "Global Origin", 72 * time.Hour, false},
+		{"other-org", "my-repo", "Global", "Global Origin", 72 * time.Hour, false},
+		{"my-org", "", "My Org", "Global Origin", 168 * time.Hour, false},
+		{"my-org", "other-repo", "My Org", "Global Origin", 168 * time.Hour, false},
+		{"my-org", "my-repo", "My Repo", "Global Origin", 168 * time.Hour, true},
+	}
+
+	for _, tc := range cases {
+		options := c.Options(tc.owner, tc.repo)
+		name := tc.owner + "/" + tc.repo
+
+		if options.Release.Label != tc.label {
+			t.Errorf("%s: label = %q, expected %q", name, options.Release.Label, tc.label)
+		}
+		if options.Release.Origin != tc.origin {
+			t.Errorf("%s: origin = %q, expected %q", name, options.Release.Origin, tc.origin)
+		}
+		if time.Duration(options.Release.ValidFor) != tc.validFor {
+			t.Errorf("%s: valid for = %v, expected %v", name, time.Duration(options.Release.ValidFor), tc.validFor)
+		}
+		if options.Release.Suites["bionic"].NotAutomatic != tc.notAutomatic {
+			t.Errorf("%s: not automatic = %v, expected %v", name, options.Release.Suites["bionic"].NotAutomatic, tc.notAutomatic)
+		}
+	}
+}
+
+func TestOptionsAreNotShared(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// suites of repository are not applied to its owner
+	if c.Options("my-org", "").Release.Suites != nil {
+		t.Error("options of repository are applied to owner")
+	}
+	if c.Options("my-org", "my-repo") == c.Options("my-org", "") {
+		t.Error("repository shares options of owner")
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	var c *Config
+	if options := c.Options("my-org", "my-repo"); options == nil || options.Release.Label != "" {
+		t.Errorf("options without config = %+v, expected defaults", options)
+	}
+
+	c, err := Parse([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options := c.Options("my-org", ""); options == nil || options.Release.Label != "" {
+		t.Errorf("options of empty config = %+v, expected defaults", options)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	configs := []string{
+		`{"relase": {"label": "Global"}}`,
+		`{"release": {"lable": "Global"}}`,
+		`{"owners": {"my-org": {"release": {"valid": "1h"}}}}`,
+		`{"owners": {"my-org": {"repositories": {"my-repo": {"retention": {"keep": 1}}}}}}`,
+		`{"owners": {"my-org": {"repositories": {"my-repo": {"suite_rules": [{"version": "x", "suite": "a"}]}}}}}`,
+	}
+
+	for _, config := range configs {
+		if _, err := Parse([]byte(config)); err == nil {
+			t.Errorf("config with unknown key is accepted: %s", config)
+		}
+	}
+}
+
+func TestInvalidValues(t *testing.T) {
+	configs := []string{
+		`{"release": {"valid_for": "a week"}}`,
+		`{"owners": {"my-org": {"components": [{"component": "dists"}]}}}`,
+		`{"components": [{"component": "nightly-debug"}]}`,
+		`{"owners": {"my-org": {"repositories": {"my-repo": {"suite_rules": [{"version": "("}]}}}}}`,
+	}
+
+	for _, config := range configs {
+		if _, err := Parse([]byte(config)); err == nil {
+			t.Errorf("invalid config is accepted: %s", config)
+		}
+	}
+}
+
+func TestRulesAreReplaced(t *testing.T) {
+	c, err := Parse([]byte(`{
+	  "components": [{"component": "nightly", "tag_name": "^nightly-"}, {"component": "lts", "target_branch": "^lts/"}],
+	  "suite_rules": [{"control_field": "X-Suite"}, {"version": "~(\\w+)$"}],
+	  "owners": {
+	    "my-org": {
+	      "components": [{"component": "edge", "prerelease": true}],
+	      "suite_rules": [{"control_field": "X-Distribution"}]
+	    }
+	  }
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	global := c.Options("other-org", "")
+	if len(global.Components) != 2 || len(global.SuiteRules) != 2 {
+		t.Errorf("global rules = %+v, %+v", global.Components, global.SuiteRules)
+	}
+
+	owner := c.Options("my-org", "")
+	if len(owner.Components) != 1 || owner.Components[0].Component != "edge" || owner.Components[0].TagName != nil {
+		t.Errorf("component rules of owner are merged with global: %+v", owner.Components)
+	}
+	if len(owner.SuiteRules) != 1 || owner.SuiteRules[0].ControlField != "X-Distribution" {
+		t.Errorf("suite rules of owner are merged with global: %+v", owner.SuiteRules)
+	}
+}
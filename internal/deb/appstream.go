@@ -0,0 +1,436 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AppStreamIconSizes are sizes of icons published in DEP-11 icon tarballs
+var AppStreamIconSizes = []string{"48x48", "64x64", "128x128"}
+
+// maxAppStreamFileSize limits files of data.tar that are kept for AppStream
+const maxAppStreamFileSize = 1 << 20
+
+const appStreamVersion = "0.12"
+
+// isAppStreamFile returns true for metainfo, desktop files and icons
+func isAppStreamFile(fileName string, size int64) bool {
+	if size > maxAppStreamFileSize {
+		return false
+	}
+
+	dir, name := path.Split(fileName)
+	switch dir {
+	case "usr/share/metainfo/", "usr/share/appdata/":
+		return strings.HasSuffix(name, ".xml")
+	case "usr/share/applications/":
+		return strings.HasSuffix(name, ".desktop")
+	}
+
+	for _, size := range AppStreamIconSizes {
+		if dir == appStreamIconDir(size) {
+			return strings.HasSuffix(name, ".png")
+		}
+	}
+	return false
+}
+
+func appStreamIconDir(size string) string {
+	return "usr/share/icons/hicolor/" + size + "/apps/"
+}
+
+type appStreamText struct {
+	Lang  string `xml:"lang,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type appStreamMarkup struct {
+	XMLName xml.Name
+	Lang    string `xml:"lang,attr"`
+	Inner   string `xml:",innerxml"`
+}
+
+type appStreamDescription struct {
+	Lang  string            `xml:"lang,attr"`
+	Items []appStreamMarkup `xml:",any"`
+}
+
+// appStreamMetainfo is a <component> (or legacy <application>) of metainfo
+type appStreamMetainfo struct {
+	Type           string                 `xml:"type,attr"`
+	ID             string                 `xml:"id"`
+	Names          []appStreamText        `xml:"name"`
+	Summaries      []appStreamText        `xml:"summary"`
+	Descriptions   []appStreamDescription `xml:"description"`
+	ProjectLicense string                 `xml:"project_license"`
+	URLs           []appStreamText        `xml:"url"`
+	Launchables    []appStreamText        `xml:"launchable"`
+	Icons          []appStreamText        `xml:"icon"`
+	Categories     []string               `xml:"categories>category"`
+}
+
+// untranslated returns a text without xml:lang
+func untranslated(texts []appStreamText) string {
+	for _, text := range texts {
+		if text.Lang == "" {
+			return strings.TrimSpace(text.Value)
+		}
+	}
+	return ""
+}
+
+func (m *appStreamMetainfo) description() string {
+	var description strings.Builder
+
+	for _, desc := range m.Descriptions {
+		if desc.Lang != "" {
+			continue
+		}
+
+		for _, item := range desc.Items {
+			if item.Lang != "" {
+				continue
+			}
+			// whitespace of markup is collapsed, as by AppStream
+			fmt.Fprintf(&description, "<%s>%s</%s>", item.XMLName.Local,
+				strings.Join(strings.Fields(item.Inner), " "), item.XMLName.Local)
+		}
+		break
+	}
+	return description.String()
+}
+
+// readDesktopEntry returns untranslated keys of [Desktop Entry]
+func readDesktopEntry(data []byte) map[string]string {
+	entry := make(map[string]string)
+	var inEntry bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if !inEntry || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			entry[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return entry
+}
+
+// AppStreamIcon is an icon copied into DEP-11 icon tarball
+type AppStreamIcon struct {
+	Name string
+	Size string
+	Data []byte
+}
+
+// AppStreamComponent is a software component described by DEP-11
+type AppStreamComponent struct {
+	Type           string
+	ID             string
+	Package        string
+	Name           string
+	Summary        string
+	Description    string
+	ProjectLicense string
+	Homepage       string
+	Categories     []string
+	DesktopID      string
+	StockIcon      string
+	Icons          []AppStreamIcon
+}
+
+var appStreamIconNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+func (p *Package) appStreamIcons(iconName string) (icons []AppStreamIcon) {
+	if iconName == "" {
+		return nil
+	}
+
+	for _, size := range AppStreamIconSizes {
+		data, ok := p.AppStream[appStreamIconDir(size)+iconName+".png"]
+		if !ok {
+			continue
+		}
+
+		icons = append(icons, AppStreamIcon{
+			Name: p.Name() + "_" + iconName + ".png",
+			Size: size,
+			Data: data,
+		})
+	}
+	return
+}
+
+// AppStreamComponents returns components described by metainfo files of package
+func (p *Package) AppStreamComponents() (components []AppStreamComponent) {
+	if p.Archive == nil {
+		return nil
+	}
+
+	for _, fileName := range sortedFileNames(p.AppStream) {
+		dir, _ := path.Split(fileName)
+		if dir != "usr/share/metainfo/" && dir != "usr/share/appdata/" {
+			continue
+		}
+
+		var metainfo appStreamMetainfo
+		if err := xml.Unmarshal(p.AppStream[fileName], &metainfo); err != nil {
+			continue
+		}
+
+		component := AppStreamComponent{
+			Type:           metainfo.Type,
+			ID:             strings.TrimSpace(metainfo.ID),
+			Package:        p.Name(),
+			Name:           untranslated(metainfo.Names),
+			Summary:        untranslated(metainfo.Summaries),
+			Description:    metainfo.description(),
+			ProjectLicense: strings.TrimSpace(metainfo.ProjectLicense),
+			Categories:     metainfo.Categories,
+		}
+		if component.ID == "" {
+			continue
+		}
+
+		switch component.Type {
+		case "", "generic":
+			component.Type = "generic"
+		case "desktop":
+			component.Type = "desktop-application"
+		}
+
+		for _, url := range metainfo.URLs {
+			if url.Type == "homepage" && url.Lang == "" {
+				component.Homepage = strings.TrimSpace(url.Value)
+			}
+		}
+
+		// desktop file is named by launchable, or by id
+		desktopIDs := []string{component.ID, component.ID + ".desktop"}
+		for _, launchable := range metainfo.Launchables {
+			if launchable.Type == "desktop-id" {
+				desktopIDs = []string{strings.TrimSpace(launchable.Value)}
+				break
+			}
+		}
+
+		for _, desktopID := range desktopIDs {
+			data, ok := p.AppStream["usr/share/applications/"+desktopID]
+			if !ok {
+				continue
+			}
+
+			entry := readDesktopEntry(data)
+			component.DesktopID = desktopID
+			if component.Name == "" {
+				component.Name = entry["Name"]
+			}
+			if component.Summary == "" {
+				component.Summary = entry["Comment"]
+			}
+			if len(component.Categories) == 0 {
+				component.Categories = strings.FieldsFunc(entry["Categories"], func(r rune) bool {
+					return r == ';'
+				})
+			}
+			component.StockIcon = entry["Icon"]
+			break
+		}
+
+		for _, icon := range metainfo.Icons {
+			if icon.Type == "stock" && component.StockIcon == "" {
+				component.StockIcon = strings.TrimSpace(icon.Value)
+			}
+		}
+		if !appStreamIconNameRegexp.MatchString(component.StockIcon) {
+			component.StockIcon = ""
+		}
+		component.Icons = p.appStreamIcons(component.StockIcon)
+
+		if component.Name == "" || component.Summary == "" {
+			continue
+		}
+		components = append(components, component)
+	}
+	return
+}
+
+// yamlEscapes are escapes of double-quoted YAML for characters that are not printable
+var yamlEscapes = map[rune]string{
+	0: `\0`, '\a': `\a`, '\b': `\b`, '\t': `\t`, '\n': `\n`, '\v': `\v`, '\f': `\f`, '\r': `\r`,
+	0x1b: `\e`, '"': `\"`, '\\': `\\`, 0x85: `\N`, 0x2028: `\L`, 0x2029: `\P`,
+}
+
+// isYAMLPrintable returns true for characters that can be written as they are, as in YAML 1.2
+func isYAMLPrintable(r rune) bool {
+	switch {
+	case r >= 0x20 && r <= 0x7e:
+		return true
+	case r >= 0xa0 && r <= 0xd7ff:
+		return true
+	case r >= 0xe000 && r <= 0xfffd && r != 0xfeff:
+		return true
+	case r >= 0x10000 && r <= 0x10ffff:
+		return true
+	}
+	return false
+}
+
+// yamlString returns a double-quoted YAML scalar, invalid UTF-8 is replaced
+func yamlString(value string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+
+	for _, r := range value {
+		if escape, ok := yamlEscapes[r]; ok {
+			quoted.WriteString(escape)
+		} else if isYAMLPrintable(r) {
+			quoted.WriteRune(r)
+		} else if r <= 0xff {
+			fmt.Fprintf(&quoted, `\x%02X`, r)
+		} else {
+			fmt.Fprintf(&quoted, `\u%04X`, r)
+		}
+	}
+
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+func (c *AppStreamComponent) Write(w io.Writer) {
+	fmt.Fprintln(w, "---")
+	fmt.Fprintln(w, "Type:", yamlString(c.Type))
+	fmt.Fprintln(w, "ID:", yamlString(c.ID))
+	fmt.Fprintln(w, "Package:", yamlString(c.Package))
+	fmt.Fprintln(w, "Name:")
+	fmt.Fprintln(w, "  C:", yamlString(c.Name))
+	fmt.Fprintln(w, "Summary:")
+	fmt.Fprintln(w, "  C:", yamlString(c.Summary))
+	if c.Description != "" {
+		fmt.Fprintln(w, "Description:")
+		fmt.Fprintln(w, "  C:", yamlString(c.Description))
+	}
+	if c.ProjectLicense != "" {
+		fmt.Fprintln(w, "ProjectLicense:", yamlString(c.ProjectLicense))
+	}
+	if c.Homepage != "" {
+		fmt.Fprintln(w, "Url:")
+		fmt.Fprintln(w, "  homepage:", yamlString(c.Homepage))
+	}
+	if len(c.Categories) > 0 {
+		fmt.Fprintln(w, "Categories:")
+		for _, category := range c.Categories {
+			fmt.Fprintln(w, "-", yamlString(category))
+		}
+	}
+	if c.DesktopID != "" {
+		fmt.Fprintln(w, "Launchable:")
+		fmt.Fprintln(w, "  desktop-id:")
+		fmt.Fprintln(w, "  -", yamlString(c.DesktopID))
+	}
+	if c.StockIcon != "" || len(c.Icons) > 0 {
+		fmt.Fprintln(w, "Icon:")
+		if c.StockIcon != "" {
+			fmt.Fprintln(w, "  stock:", yamlString(c.StockIcon))
+		}
+		if len(c.Icons) > 0 {
+			fmt.Fprintln(w, "  cached:")
+		}
+		for _, icon := range c.Icons {
+			size := strings.SplitN(icon.Size, "x", 2)
+			fmt.Fprintln(w, "  - name:", yamlString(icon.Name))
+			fmt.Fprintln(w, "    width:", size[0])
+			fmt.Fprintln(w, "    height:", size[1])
+		}
+	}
+}
+
+// appStreamComponents returns the newest components of packages in component
+func (p *Repository) appStreamComponents(component, architecture string) (components []AppStreamComponent) {
+	written := make(map[string]struct{})
+
+	// packages are sorted from the oldest version
+	for i := len(p.debs) - 1; i >= 0; i-- {
+		deb := p.debs[i]
+		if deb.Type != BinaryPackage || len(deb.AppStream) == 0 {
+			continue
+		}
+		if architecture != "" && !deb.MatchingArchitecture(architecture) {
+			continue
+		}
+		if !deb.MatchingComponents(component) {
+			continue
+		}
+
+		for _, appStreamComponent := range deb.AppStreamComponents() {
+			if _, ok := written[appStreamComponent.ID]; ok {
+				continue
+			}
+			written[appStreamComponent.ID] = struct{}{}
+			components = append(components, appStreamComponent)
+		}
+	}
+	return
+}
+
+func (p *Repository) hasAppStream(component string) bool {
+	for _, deb := range p.debs {
+		if deb.Type == BinaryPackage && len(deb.AppStream) > 0 && deb.MatchingComponents(component) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Repository) appStreamOrigin(component string) string {
+	return strings.ToLower(p.getOrigin()) + "-" + p.suite + "-" + component
+}
+
+// WriteAppStream writes DEP-11 Components- with all components of packages
+func (p *Repository) WriteAppStream(w io.Writer, component, architecture string) error {
+	fmt.Fprintln(w, "---")
+	fmt.Fprintln(w, "File: DEP-11")
+	fmt.Fprintln(w, "Version:", yamlString(appStreamVersion))
+	fmt.Fprintln(w, "Origin:", yamlString(p.appStreamOrigin(component)))
+
+	for _, appStreamComponent := range p.appStreamComponents(component, architecture) {
+		appStreamComponent.Write(w)
+	}
+	return nil
+}
+
+// WriteAppStreamIcons writes DEP-11 icons- tarball of all architectures
+func (p *Repository) WriteAppStreamIcons(w io.Writer, component, size string) error {
+	icons := make(map[string][]byte)
+
+	for _, appStreamComponent := range p.appStreamComponents(component, "") {
+		for _, icon := range appStreamComponent.Icons {
+			if _, ok := icons[icon.Name]; !ok && icon.Size == size {
+				icons[icon.Name] = icon.Data
+			}
+		}
+	}
+
+	data, err := writeFilesTar(icons)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,111 @@
+package deb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestYAMLString(t *testing.T) {
+	cases := []struct {
+		value, expected string
+	}{
+		{"", `""`},
+		{"Hello", `"Hello"`},
+		{`quote " and \ backslash`, `"quote \" and \\ backslash"`},
+		{"Café – ünïcode 日本語 🎉", `"Café – ünïcode 日本語 🎉"`},
+		{"tab\tnew\nline\r", `"tab\tnew\nline\r"`},
+		{"\x00\x07\x1b\x7f", `"\0\a\e\x7F"`},
+		{"\u0085\u2028\u2029", `"\N\L\P"`},
+		{"\u0080\u009f\ufeff\ufffe", `"\x80\x9F\uFEFF\uFFFE"`},
+		{"invalid \xff utf-8", "\"invalid � utf-8\""},
+	}
+
+	for _, c := range cases {
+		if quoted := yamlString(c.value); quoted != c.expected {
+			t.Errorf("yamlString(%q) = %s, expected %s", c.value, quoted, c.expected)
+		}
+	}
+}
+
+const testMetainfo = `<?xml version="1.0" encoding="UTF-8"?>
+<component type="desktop-application">
+  <id>org.example.Hello</id>
+  <metadata_license>CC0-1.0</metadata_license>
+  <project_license>GPL-3.0-or-later</project_license>
+  <name>Hello Café</name>
+  <name xml:lang="de">Hallo Café</name>
+  <summary>Says "hello" in 日本語</summary>
+  <description>
+    <p>
+      Hello greets the world,
+      in many languages – ünïcode included.
+    </p>
+    <p xml:lang="de">Hallo grüßt die Welt.</p>
+    <ul>
+      <li>Friendly</li>
+      <li>Fast &amp; small</li>
+    </ul>
+  </description>
+  <url type="homepage">https://example.org/hello</url>
+  <launchable type="desktop-id">org.example.Hello.desktop</launchable>
+</component>
+`
+
+const testDesktopEntry = `[Desktop Entry]
+Name=Hello
+Comment=Greets the world
+Icon=org.example.Hello
+Categories=Utility;GTK;
+`
+
+const testAppStream = `---
+Type: "desktop-application"
+ID: "org.example.Hello"
+Package: "hello-gui"
+Name:
+  C: "Hello Café"
+Summary:
+  C: "Says \"hello\" in 日本語"
+Description:
+  C: "<p>Hello greets the world, in many languages – ünïcode included.</p><ul><li>Friendly</li> <li>Fast &amp; small</li></ul>"
+ProjectLicense: "GPL-3.0-or-later"
+Url:
+  homepage: "https://example.org/hello"
+Categories:
+- "Utility"
+- "GTK"
+Launchable:
+  desktop-id:
+  - "org.example.Hello.desktop"
+Icon:
+  stock: "org.example.Hello"
+  cached:
+  - name: "hello-gui_org.example.Hello.png"
+    width: 64
+    height: 64
+`
+
+func TestAppStreamComponents(t *testing.T) {
+	p := &Package{
+		Archive: &Archive{
+			AppStream: map[string][]byte{
+				"usr/share/metainfo/org.example.Hello.metainfo.xml":        []byte(testMetainfo),
+				"usr/share/applications/org.example.Hello.desktop":         []byte(testDesktopEntry),
+				"usr/share/icons/hicolor/64x64/apps/org.example.Hello.png": []byte("png"),
+			},
+		},
+		paragraphs: map[string]string{"Package": "hello-gui"},
+	}
+
+	components := p.AppStreamComponents()
+	if len(components) != 1 {
+		t.Fatalf("expected a single component, got %d", len(components))
+	}
+
+	var output bytes.Buffer
+	components[0].Write(&output)
+
+	if output.String() != testAppStream {
+		t.Errorf("unexpected DEP-11:\n%s\nexpected:\n%s", output.String(), testAppStream)
+	}
+}
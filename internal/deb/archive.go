@@ -2,6 +2,7 @@ package deb
 
 import (
 	"archive/tar"
+	"bufio"
 	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
@@ -12,10 +13,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/blakesmith/ar"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stapelberg/godebiancontrol"
 	"github.com/ulikunitz/xz"
 
 	"github.com/ayufan/debian-repository/internal/multi_hash"
@@ -59,8 +64,39 @@ func readDebianBinary(r io.Reader) (string, error) {
 	return version, nil
 }
 
-func readControlTar(r io.Reader) ([]byte, error) {
+func readControlTar(r io.Reader) ([]byte, map[string][]byte, error) {
+	files := make(map[string][]byte)
+
 	rd := tar.NewReader(r)
+	for {
+		header, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		fileName := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		files[fileName], err = ioutil.ReadAll(rd)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if files["control"] == nil {
+		return nil, nil, errors.New("control not found in control.tar.gz")
+	}
+	return files["control"], files, nil
+}
+
+func readFilesTar(data []byte) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	rd := tar.NewReader(bytes.NewReader(data))
 	for {
 		header, err := rd.Next()
 		if err == io.EOF {
@@ -69,40 +105,209 @@ func readControlTar(r io.Reader) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		if header.Name == "control" || header.Name == "./control" {
-			return ioutil.ReadAll(rd)
+
+		files[header.Name], err = ioutil.ReadAll(rd)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return nil, errors.New("control not found in control.tar.gz")
+	return files, nil
 }
 
-func readControlTarGz(r io.Reader) ([]byte, error) {
-	gz, err := gzip.NewReader(r)
+func writeFilesTar(files map[string][]byte) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	wr := tar.NewWriter(&buffer)
+	for _, fileName := range sortedFileNames(files) {
+		err := wr.WriteHeader(&tar.Header{
+			Name: fileName,
+			Mode: 0644,
+			Size: int64(len(files[fileName])),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = wr.Write(files[fileName])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := wr.Close()
 	if err != nil {
 		return nil, err
 	}
-	defer gz.Close()
+	return buffer.Bytes(), nil
+}
 
-	return readControlTar(gz)
+func sortedFileNames(files map[string][]byte) []string {
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
 }
 
-func readControlTarXz(r io.Reader) ([]byte, error) {
-	xz, err := xz.NewReader(r)
+func readDataTar(r io.Reader, packageName string) (files []string, changelog []byte, appStream map[string][]byte, err error) {
+	changelogName := "usr/share/doc/" + packageName + "/changelog.Debian.gz"
+
+	rd := tar.NewReader(r)
+	for {
+		header, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		// Contents- lists paths relative to the root, without a leading "./"
+		fileName := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if fileName == "" {
+			continue
+		}
+		files = append(files, fileName)
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if fileName == changelogName {
+			// a corrupt changelog is not needed to publish the package
+			changelog, err = readGz(rd)
+			if err != nil {
+				log.Println("Ignoring", changelogName+":", err)
+				changelog = nil
+			}
+		} else if isAppStreamFile(fileName, header.Size) {
+			data, err := ioutil.ReadAll(rd)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%s: %v", fileName, err)
+			}
+			if appStream == nil {
+				appStream = make(map[string][]byte)
+			}
+			appStream[fileName] = data
+		}
+	}
+	return files, changelog, appStream, nil
+}
+
+func readGz(r io.Reader) ([]byte, error) {
+	gz, err := gzip.NewReader(r)
 	if err != nil {
 		return nil, err
 	}
+	defer gz.Close()
+
+	return ioutil.ReadAll(gz)
+}
 
-	return readControlTar(xz)
+// newLineScanner returns a scanner of lines of data,
+// a line can be as long as the whole data, ex. a description or a list of files
+func newLineScanner(data []byte) *bufio.Scanner {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	return scanner
 }
 
-func readControlTarBzip2(r io.Reader) ([]byte, error) {
-	bzip2 := bzip2.NewReader(r)
+func readPackageName(control []byte) string {
+	paragraphs, err := godebiancontrol.Parse(bytes.NewBuffer(control))
+	if err != nil || len(paragraphs) == 0 {
+		return ""
+	}
+	return paragraphs[0]["Package"]
+}
+
+func decompressTar(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "":
+		return ioutil.NopCloser(r), nil
+
+	case "gz":
+		return gzip.NewReader(r)
+
+	case "xz":
+		xz, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(xz), nil
 
-	return readControlTar(bzip2)
+	case "bz2":
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+
+	case "zst":
+		zst, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zst.IOReadCloser(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
+}
+
+// splitMember returns a base name and compression of an ar member,
+// ex. control.tar.gz/ => control.tar, gz
+func splitMember(name string) (string, string) {
+	name = strings.TrimSuffix(name, "/")
+	if strings.HasSuffix(name, ".tar") {
+		return name, ""
+	}
+	if idx := strings.LastIndex(name, ".tar."); idx >= 0 {
+		return name[0 : idx+4], name[idx+5:]
+	}
+	return name, ""
 }
 
 type Archive struct {
-	Control []byte
+	Control   []byte
+	Files     []string
+	Changelog []byte
+
+	// ControlFiles holds all members of control.tar, ex. postinst or md5sums
+	ControlFiles map[string][]byte
+
+	// AppStream holds metainfo, desktop files and icons of data.tar
+	AppStream map[string][]byte
+
+	// Compressions maps a member, ex. control.tar, to its detected compression
+	Compressions map[string]string
+}
+
+func (d *Archive) readControl(r io.Reader, compression string) error {
+	tr, err := decompressTar(r, compression)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	d.Control, d.ControlFiles, err = readControlTar(tr)
+	return err
+}
+
+// readData reads data.tar, nothing of it is kept if it cannot be read
+func (d *Archive) readData(r io.Reader, compression string) error {
+	tr, err := decompressTar(r, compression)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	files, changelog, appStream, err := readDataTar(tr, readPackageName(d.Control))
+	if err != nil {
+		return err
+	}
+
+	d.Files, d.Changelog, d.AppStream = files, changelog, appStream
+	return nil
 }
 
 func (d *Archive) parseArchive(r io.Reader) error {
@@ -120,21 +325,34 @@ func (d *Archive) parseArchive(r io.Reader) error {
 	err := enumerateDebArchive(pr, func(name string, r io.Reader) (err error) {
 		if name == "debian-binary" || name == "debian-binary/" {
 			debianVersion, err = readDebianBinary(r)
-		} else if name == "control.tar" || name == "control.tar/" {
-			d.Control, err = readControlTar(r)
-		} else if name == "control.tar.gz" || name == "control.tar.gz/" {
-			d.Control, err = readControlTarGz(r)
-		} else if name == "control.tar.xz" || name == "control.tar.xz/" {
-			d.Control, err = readControlTarXz(r)
-		} else if name == "control.tar.bz2" || name == "control.tar.bz2/" {
-			d.Control, err = readControlTarBzip2(r)
-		}
-		return
+			return
+		}
+
+		baseName, compression := splitMember(name)
+		if baseName != "control.tar" && baseName != "data.tar" {
+			return
+		}
+
+		if d.Compressions == nil {
+			d.Compressions = make(map[string]string)
+		}
+		d.Compressions[baseName] = compression
+
+		if baseName == "control.tar" {
+			return d.readControl(r, compression)
+		}
+
+		// data.tar is only used for Contents, changelog and AppStream,
+		// so a package is published without them if it cannot be read
+		if err := d.readData(r, compression); err != nil {
+			log.Println("Ignoring", name+":", err)
+		}
+		return nil
 	})
 
 	if err == nil {
 		if debianVersion == "" || d.Control == nil {
-			err = errors.New("missing debian-binary or control.tar.gz/xz")
+			err = errors.New("missing debian-binary or control.tar.{gz,xz,bz2,zst}")
 		}
 	}
 	if err == nil {
@@ -151,12 +369,72 @@ func (d *Archive) readFromCache(tag string) error {
 		return err
 	}
 
+	contents, err := repository_cache.Read(tag, "contents")
+	if err != nil {
+		return err
+	}
+
+	changelog, err := repository_cache.Read(tag, "changelog")
+	if err != nil {
+		return err
+	}
+
+	controlFiles, err := repository_cache.Read(tag, "control-files")
+	if err != nil {
+		return err
+	}
+
+	appStream, err := repository_cache.Read(tag, "appstream")
+	if err != nil {
+		return err
+	}
+
 	d.Control = data
-	return nil
+	d.Files = strings.Split(string(contents), "\n")
+	if len(contents) == 0 {
+		d.Files = nil
+	}
+	d.Changelog = changelog
+	d.ControlFiles, err = readFilesTar(controlFiles)
+	if err != nil {
+		return err
+	}
+	d.AppStream, err = readFilesTar(appStream)
+	return err
 }
 
 func (d *Archive) writeToCache(tag string) error {
-	return repository_cache.Write(tag, "control", d.Control)
+	err := repository_cache.Write(tag, "control", d.Control)
+	if err != nil {
+		return err
+	}
+
+	err = repository_cache.Write(tag, "contents", []byte(strings.Join(d.Files, "\n")))
+	if err != nil {
+		return err
+	}
+
+	err = repository_cache.Write(tag, "changelog", d.Changelog)
+	if err != nil {
+		return err
+	}
+
+	controlFiles, err := writeFilesTar(d.ControlFiles)
+	if err != nil {
+		return err
+	}
+
+	err = repository_cache.Write(tag, "control-files", controlFiles)
+	if err != nil {
+		return err
+	}
+
+	appStream, err := writeFilesTar(d.AppStream)
+	if err != nil {
+		return err
+	}
+
+	return repository_cache.Write(tag, "appstream", appStream)
 }
 
 func Read(r io.Reader) (*Archive, error) {
@@ -0,0 +1,148 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/blakesmith/ar"
+)
+
+const testControl = `Package: hello
+Version: 1.0
+Architecture: amd64
+Maintainer: Jane Doe <jane@example.org>
+Description: greets the world
+`
+
+func testTar(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	wr := tar.NewWriter(&buffer)
+	for fileName, content := range files {
+		err := wr.WriteHeader(&tar.Header{
+			Name:     "./" + fileName,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wr.Write([]byte(content))
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func testGz(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	gz.Write(data)
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+type testMember struct {
+	name string
+	data []byte
+}
+
+// testDeb returns an ar archive with members in order
+func testDeb(t *testing.T, members ...testMember) []byte {
+	var buffer bytes.Buffer
+	wr := ar.NewWriter(&buffer)
+	if err := wr.WriteGlobalHeader(); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		err := wr.WriteHeader(&ar.Header{Name: member.name, Mode: 0644, Size: int64(len(member.data))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wr.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func TestReadDataTar(t *testing.T) {
+	controlTar := testGz(t, testTar(t, map[string]string{"control": testControl}))
+	dataTar := testGz(t, testTar(t, map[string]string{"usr/bin/hello": "#!/bin/sh\n"}))
+
+	d, err := Read(bytes.NewReader(testDeb(t,
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.gz", controlTar},
+		testMember{"data.tar.gz", dataTar},
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Files) != 1 || d.Files[0] != "usr/bin/hello" {
+		t.Errorf("Files = %q, expected usr/bin/hello", d.Files)
+	}
+}
+
+func TestReadCorruptChangelog(t *testing.T) {
+	controlTar := testGz(t, testTar(t, map[string]string{"control": testControl}))
+	dataTar := testGz(t, testTar(t, map[string]string{
+		"usr/bin/hello": "#!/bin/sh\n",
+		"usr/share/doc/hello/changelog.Debian.gz": "not gzipped",
+		"usr/share/doc/hello/copyright":           "Public Domain\n",
+	}))
+
+	d, err := Read(bytes.NewReader(testDeb(t,
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.gz", controlTar},
+		testMember{"data.tar.gz", dataTar},
+	)))
+	if err != nil {
+		t.Fatal("package with corrupt changelog is rejected:", err)
+	}
+	if d.Changelog != nil {
+		t.Errorf("Changelog = %q, expected none", d.Changelog)
+	}
+	if len(d.Files) != 3 {
+		t.Errorf("Files = %q, expected all files of data.tar", d.Files)
+	}
+}
+
+func TestReadUnsupportedDataTar(t *testing.T) {
+	controlTar := testGz(t, testTar(t, map[string]string{"control": testControl}))
+
+	d, err := Read(bytes.NewReader(testDeb(t,
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.gz", controlTar},
+		testMember{"data.tar.lzma", []byte("not supported")},
+	)))
+	if err != nil {
+		t.Fatal("package with unsupported data.tar is rejected:", err)
+	}
+	if d.Control == nil {
+		t.Error("control is not read")
+	}
+	if d.Files != nil || d.Changelog != nil || d.AppStream != nil {
+		t.Error("data.tar is read")
+	}
+	if d.Compressions["data.tar"] != "lzma" {
+		t.Errorf("compression of data.tar = %q, expected lzma", d.Compressions["data.tar"])
+	}
+}
+
+func TestReadUnsupportedControlTar(t *testing.T) {
+	dataTar := testGz(t, testTar(t, map[string]string{"usr/bin/hello": "#!/bin/sh\n"}))
+
+	_, err := Read(bytes.NewReader(testDeb(t,
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.lzma", []byte("not supported")},
+		testMember{"data.tar.gz", dataTar},
+	)))
+	if err == nil {
+		t.Error("package with unsupported control.tar is accepted")
+	}
+}
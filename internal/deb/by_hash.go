@@ -0,0 +1,100 @@
+package deb
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ayufan/debian-repository/internal/by_hash_cache"
+	"github.com/ayufan/debian-repository/internal/multi_hash"
+)
+
+// ByHash keeps indices of previous generations available for Acquire-By-Hash
+var ByHash *by_hash_cache.Cache
+
+// byHashPath returns a path of file: <dir>/by-hash/<hash>/<digest>
+func byHashPath(fileName, hashName string, hash *multi_hash.MultiHash) string {
+	return path.Join(path.Dir(fileName), "by-hash", hashName, hash.Hex(hashName))
+}
+
+// parseByHashPath returns a directory of a by-hash path
+func parseByHashPath(fileName string) (dir string, ok bool) {
+	parts := strings.Split(fileName, "/")
+	if len(parts) < 3 || parts[len(parts)-3] != "by-hash" {
+		return "", false
+	}
+
+	return path.Join(parts[0 : len(parts)-3]...), true
+}
+
+// IsByHashPath returns true if file is requested by hash, its content never changes
+func IsByHashPath(fileName string) bool {
+	_, ok := parseByHashPath(fileName)
+	return ok
+}
+
+func (p *Repository) byHashKey(fileName string) string {
+	return strings.Join([]string{p.owner, p.repo, p.suite, p.component, fileName}, "/")
+}
+
+func (p *Repository) storeByHash(fileName string, hash *multi_hash.MultiHash) {
+	var keys []string
+	for _, hashOpt := range multi_hash.Hashes {
+		keys = append(keys, p.byHashKey(byHashPath(fileName, hashOpt.Name, hash)))
+	}
+	ByHash.Add(keys, hash.Bytes())
+}
+
+// ByHashFile returns a file of current generation, or one of recent generations
+func (p *Repository) ByHashFile(fileName string) (*RepositoryFile, error) {
+	dir, ok := parseByHashPath(fileName)
+	if !ok {
+		return nil, nil
+	}
+
+	for indexName, fileOpt := range p.Files() {
+		if path.Dir(indexName) != path.Clean(dir) {
+			continue
+		}
+
+		hash, err := fileOpt.Hash()
+		if err != nil {
+			return nil, err
+		}
+		for _, hashOpt := range multi_hash.Hashes {
+			if byHashPath(indexName, hashOpt.Name, hash) == fileName {
+				return fileOpt, nil
+			}
+		}
+	}
+
+	data, found := ByHash.Get(p.byHashKey(fileName))
+	if !found {
+		return nil, nil
+	}
+
+	return &RepositoryFile{
+		Writer: func(w io.Writer) error {
+			_, err := io.Copy(w, bytes.NewReader(data))
+			return err
+		},
+	}, nil
+}
+
+// File returns an index, a signed release, a patch or a file requested by hash
+func (p *Repository) File(fileName string) (*RepositoryFile, error) {
+	if p.suite != "" && !p.options.SuiteDiscovery.IsAllowed(p.suite) {
+		return nil, nil
+	}
+
+	if file := p.AllFiles()[fileName]; file != nil {
+		return file, nil
+	}
+
+	if file, err := p.PdiffFile(fileName); file != nil || err != nil {
+		return file, err
+	}
+
+	return p.ByHashFile(fileName)
+}
@@ -0,0 +1,101 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const changelogDateFormat = "Mon, 02 Jan 2006 15:04:05 -0700"
+
+// ChangelogPath returns a path as expanded by apt from @CHANGEPATH@,
+// ex. releases/h/hello/hello_1.0-1
+func ChangelogPath(component, sourceName, sourceVersion string) string {
+	prefix := sourceName
+	if strings.HasPrefix(sourceName, "lib") && len(sourceName) > 3 {
+		prefix = sourceName[0:4]
+	} else if len(sourceName) > 0 {
+		prefix = sourceName[0:1]
+	}
+
+	return strings.Join([]string{component, prefix, sourceName, sourceName + "_" + stripEpoch(sourceVersion)}, "/")
+}
+
+func stripEpoch(version string) string {
+	if idx := strings.Index(version, ":"); idx >= 0 {
+		return version[idx+1:]
+	}
+	return version
+}
+
+func (p *Package) ChangelogPath() string {
+	return ChangelogPath(p.Component, p.SourceName(), p.SourceVersion())
+}
+
+// Changelog returns a changelog.Debian of package,
+// or the GitHub release notes converted to the Debian changelog format
+func (p *Package) Changelog() []byte {
+	if p.Archive != nil && len(p.Archive.Changelog) > 0 {
+		return p.Archive.Changelog
+	}
+
+	distribution := strings.Join(p.Suites, " ")
+	if distribution == "" || contains(p.Suites, allSuites) {
+		distribution = "unstable"
+	}
+
+	maintainer := p.Maintainer()
+	if maintainer == "" {
+		maintainer = "GitHub <noreply@github.com>"
+	}
+
+	date := p.PublishedAt
+	if date.IsZero() {
+		date = p.UpdatedAt
+	}
+
+	buffer := new(bytes.Buffer)
+	fmt.Fprintf(buffer, "%s (%s) %s; urgency=medium\n\n", p.SourceName(), p.SourceVersion(), distribution)
+
+	entries := releaseBodyToChangelog(p.ReleaseBody)
+	if len(entries) == 0 {
+		entries = []string{"  * Release " + p.TagName}
+	}
+	for _, entry := range entries {
+		fmt.Fprintln(buffer, entry)
+	}
+
+	fmt.Fprintf(buffer, "\n -- %s  %s\n", maintainer, date.UTC().Format(changelogDateFormat))
+	return buffer.Bytes()
+}
+
+// releaseBodyToChangelog converts markdown of release notes
+// into the changelog entries: top-level items are written as `  * `
+// and nested items as `    - `
+func releaseBodyToChangelog(body string) (entries []string) {
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		text := strings.TrimLeft(line, " \t")
+		if text == "" || strings.Trim(text, "-=*_") == "" {
+			continue
+		}
+
+		nested := len(line)-len(text) >= 2
+		text = strings.TrimSpace(strings.TrimLeft(text, "#"))
+		for _, bullet := range []string{"- ", "* ", "+ "} {
+			text = strings.TrimPrefix(text, bullet)
+		}
+		if text == "" {
+			continue
+		}
+
+		if nested {
+			entries = append(entries, "    - "+text)
+		} else {
+			entries = append(entries, "  * "+text)
+		}
+	}
+	return
+}
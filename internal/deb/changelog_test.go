@@ -0,0 +1,89 @@
+package deb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangelogPath(t *testing.T) {
+	cases := []struct {
+		component, sourceName, sourceVersion string
+		expected                             string
+	}{
+		{"releases", "hello", "1.0-1", "releases/h/hello/hello_1.0-1"},
+		{"releases", "libfoo", "2.0", "releases/libf/libfoo/libfoo_2.0"},
+		{"pre-releases", "lib", "1.0", "pre-releases/l/lib/lib_1.0"},
+		{"releases", "liberation", "1:2.1~rc1", "releases/libe/liberation/liberation_2.1~rc1"},
+		{"releases-debug", "xlib", "1.0", "releases-debug/x/xlib/xlib_1.0"},
+	}
+
+	for _, c := range cases {
+		if changelogPath := ChangelogPath(c.component, c.sourceName, c.sourceVersion); changelogPath != c.expected {
+			t.Errorf("ChangelogPath(%q, %q, %q) = %q, expected %q",
+				c.component, c.sourceName, c.sourceVersion, changelogPath, c.expected)
+		}
+	}
+}
+
+func TestPackageChangelogPath(t *testing.T) {
+	p := testPackage("libfoo1", "1.0-1+b1", "bookworm")
+	p.paragraphs["Source"] = "libfoo (1.0-1)"
+
+	if changelogPath := p.ChangelogPath(); changelogPath != "releases/libf/libfoo/libfoo_1.0-1" {
+		t.Errorf("ChangelogPath() = %q, expected one of source", changelogPath)
+	}
+}
+
+func TestReleaseBodyToChangelog(t *testing.T) {
+	p := testPackage("hello", "1.0-1", "bookworm", "bullseye")
+	p.paragraphs["Maintainer"] = "Jane Doe <jane@example.org>"
+	p.TagName = "v1.0"
+	p.PublishedAt = time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	p.ReleaseBody = "## Changes\r\n" +
+		"\r\n" +
+		"- Fix a crash\r\n" +
+		"  * when started without arguments\r\n" +
+		"+ Add `--version`\r\n" +
+		"---\r\n" +
+		"Thanks to all contributors!\r\n"
+
+	expected := "hello (1.0-1) bookworm bullseye; urgency=medium\n" +
+		"\n" +
+		"  * Changes\n" +
+		"  * Fix a crash\n" +
+		"    - when started without arguments\n" +
+		"  * Add `--version`\n" +
+		"  * Thanks to all contributors!\n" +
+		"\n" +
+		" -- Jane Doe <jane@example.org>  Fri, 01 Mar 2024 11:30:00 +0000\n"
+
+	if changelog := string(p.Changelog()); changelog != expected {
+		t.Errorf("changelog:\n%s\nexpected:\n%s", changelog, expected)
+	}
+}
+
+func TestEmptyReleaseBodyToChangelog(t *testing.T) {
+	p := testPackage("hello", "1.0-1", allSuites)
+	p.TagName = "v1.0"
+	p.UpdatedAt = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	expected := "hello (1.0-1) unstable; urgency=medium\n" +
+		"\n" +
+		"  * Release v1.0\n" +
+		"\n" +
+		" -- GitHub <noreply@github.com>  Fri, 01 Mar 2024 12:30:00 +0000\n"
+
+	if changelog := string(p.Changelog()); changelog != expected {
+		t.Errorf("changelog:\n%s\nexpected:\n%s", changelog, expected)
+	}
+}
+
+func TestChangelogOfArchive(t *testing.T) {
+	p := testPackage("hello", "1.0-1", "bookworm")
+	p.Archive = &Archive{Changelog: []byte("hello (1.0-1) bookworm; urgency=low\n")}
+	p.ReleaseBody = "- ignored"
+
+	if changelog := string(p.Changelog()); changelog != string(p.Archive.Changelog) {
+		t.Errorf("changelog = %q, expected one of archive", changelog)
+	}
+}
@@ -0,0 +1,104 @@
+package deb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+const defaultComponent = "releases"
+const defaultPrereleaseComponent = "pre-releases"
+const debugComponentSuffix = "-debug"
+
+// reservedComponents are used by routes of repository, so components cannot be named so
+var reservedComponents = []string{"archive.key", "changelogs", "control", "dists", "pool"}
+
+// ComponentRule assigns releases and assets to component,
+// all patterns that are set have to match
+type ComponentRule struct {
+	Component string `json:"component"`
+
+	TagName      *Regexp `json:"tag_name"`
+	TargetBranch *Regexp `json:"target_branch"`
+	AssetLabel   *Regexp `json:"asset_label"`
+	FileName     *Regexp `json:"file_name"`
+
+	// ReleaseBody matches a marker in description of release, ex. "\\[nightly\\]"
+	ReleaseBody *Regexp `json:"release_body"`
+
+	Prerelease *bool `json:"prerelease"`
+}
+
+// ComponentRules are checked in order, the first matching one is used
+type ComponentRules []ComponentRule
+
+func (r *ComponentRules) UnmarshalJSON(data []byte) error {
+	rules, err := unmarshalRules[ComponentRule](data)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if rule.Component == "" {
+			return errors.New("component rule without component")
+		}
+		if strings.ContainsAny(rule.Component, "/ ") {
+			return fmt.Errorf("invalid component name: %q", rule.Component)
+		}
+		if contains(reservedComponents, rule.Component) {
+			return fmt.Errorf("reserved component name: %q", rule.Component)
+		}
+		// debug symbols of component are published in <component>-debug
+		if strings.HasSuffix(rule.Component, debugComponentSuffix) {
+			return fmt.Errorf("component name cannot end with %s: %q", debugComponentSuffix, rule.Component)
+		}
+	}
+
+	*r = rules
+	return nil
+}
+
+func matchingPattern(pattern *Regexp, value string) bool {
+	return pattern == nil || pattern.MatchString(value)
+}
+
+func (r *ComponentRule) Matching(release *github.RepositoryRelease, asset *github.ReleaseAsset) bool {
+	if r.Prerelease != nil && *r.Prerelease != release.GetPrerelease() {
+		return false
+	}
+
+	return matchingPattern(r.TagName, release.GetTagName()) &&
+		matchingPattern(r.TargetBranch, release.GetTargetCommitish()) &&
+		matchingPattern(r.AssetLabel, asset.GetLabel()) &&
+		matchingPattern(r.FileName, asset.GetName()) &&
+		matchingPattern(r.ReleaseBody, release.GetBody())
+}
+
+// Component returns a component of release asset,
+// by default it is releases or pre-releases
+func (o *Options) Component(release *github.RepositoryRelease, asset *github.ReleaseAsset) string {
+	for _, rule := range o.Components {
+		if rule.Matching(release, asset) {
+			return rule.Component
+		}
+	}
+
+	if release.GetPrerelease() {
+		return defaultPrereleaseComponent
+	}
+	return defaultComponent
+}
+
+// ComponentNames returns a list of default components and all used by rules
+func (o *Options) ComponentNames() []string {
+	components := []string{defaultComponent, defaultPrereleaseComponent}
+
+	for _, rule := range o.Components {
+		if !contains(components, rule.Component) {
+			components = append(components, rule.Component)
+		}
+	}
+	return components
+}
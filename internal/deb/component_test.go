@@ -0,0 +1,67 @@
+package deb
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestComponentRules(t *testing.T) {
+	options := DefaultOptions()
+	err := strictUnmarshal([]byte(`{"components": [
+		{"component": "nightly", "tag_name": "^nightly-", "prerelease": true},
+		{"component": "lts", "target_branch": "^lts/", "file_name": "_amd64\\.deb$"},
+		{"component": "edge", "asset_label": "^edge", "release_body": "\\[edge\\]"}
+	]}`), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		tagName    string
+		branch     string
+		prerelease bool
+		body       string
+		fileName   string
+		label      string
+		expected   string
+	}{
+		{"all patterns of rule match", "nightly-1", "main", true, "", "hello_1.0_amd64.deb", "", "nightly"},
+		{"prerelease does not match", "nightly-1", "main", false, "", "hello_1.0_amd64.deb", "", defaultComponent},
+		{"branch and file name match", "v1.0", "lts/1", false, "", "hello_1.0_amd64.deb", "", "lts"},
+		{"file name does not match", "v1.0", "lts/1", false, "", "hello_1.0_arm64.deb", "", defaultComponent},
+		{"label and body match", "v1.0", "main", false, "Notes [edge]", "hello_1.0_amd64.deb", "edge build", "edge"},
+		{"the first matching rule is used", "nightly-1", "lts/1", true, "", "hello_1.0_amd64.deb", "", "nightly"},
+		{"default of prerelease", "v1.0-rc1", "main", true, "", "hello_1.0_amd64.deb", "", defaultPrereleaseComponent},
+	}
+
+	for _, c := range cases {
+		release := &github.RepositoryRelease{
+			TagName:         github.String(c.tagName),
+			TargetCommitish: github.String(c.branch),
+			Prerelease:      github.Bool(c.prerelease),
+			Body:            github.String(c.body),
+		}
+		asset := &github.ReleaseAsset{Name: github.String(c.fileName), Label: github.String(c.label)}
+
+		if component := options.Component(release, asset); component != c.expected {
+			t.Errorf("%s: component = %q, expected %q", c.name, component, c.expected)
+		}
+	}
+}
+
+func TestInvalidComponentRules(t *testing.T) {
+	configs := []string{
+		`{"components": [{"tag_name": "^v"}]}`,
+		`{"components": [{"component": "my component"}]}`,
+		`{"components": [{"component": "pool"}]}`,
+		`{"components": [{"component": "nightly-debug"}]}`,
+	}
+
+	for _, config := range configs {
+		if err := strictUnmarshal([]byte(config), DefaultOptions()); err == nil {
+			t.Errorf("invalid component rule is accepted: %s", config)
+		}
+	}
+}
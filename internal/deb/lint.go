@@ -0,0 +1,290 @@
+package deb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Severity int
+
+const (
+	LintWarning Severity = iota
+	LintError
+)
+
+func (s Severity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a problem found in a package, errors keep package out of the index
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+func (f Finding) Error() string {
+	return f.String()
+}
+
+type lintRule struct {
+	name     string
+	severity Severity
+	check    func(p *Package) []string
+}
+
+// DisabledLintRules lists names of rules that are not checked
+var DisabledLintRules []string
+
+var lintRules = []lintRule{
+	{"package-name", LintError, lintPackageName},
+	{"version", LintError, lintVersion},
+	{"version-format", LintWarning, lintVersionFormat},
+	{"architecture", LintError, lintArchitecture},
+	{"architecture-filename", LintError, lintArchitectureFileName},
+	{"relationships", LintError, lintRelationships},
+	{"priority", LintWarning, lintPriority},
+	{"section", LintWarning, lintSection},
+	{"maintainer", LintWarning, lintMaintainer},
+}
+
+// ValidateLintRules returns an error if any of names is not a rule
+func ValidateLintRules(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, rule := range lintRules {
+			if rule.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown lint rule: %q", name)
+		}
+	}
+	return nil
+}
+
+var packageNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+var architectureRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+var relationRegexp = regexp.MustCompile(`^([a-z0-9][a-z0-9+.-]*)(:[a-z0-9-]+)?` +
+	`\s*(\(\s*(<<|<=|=|>=|>>|<|>)\s*([^)\s]+)\s*\))?` +
+	`\s*(\[[^\]]+\])?` +
+	`\s*(<[^>]+>\s*)*$`)
+
+var binaryRelationships = []string{
+	"Pre-Depends", "Depends", "Recommends", "Suggests", "Enhances",
+	"Breaks", "Conflicts", "Replaces", "Provides",
+}
+
+var sourceRelationships = []string{
+	"Build-Depends", "Build-Depends-Indep", "Build-Depends-Arch",
+	"Build-Conflicts", "Build-Conflicts-Indep", "Build-Conflicts-Arch",
+}
+
+var knownPriorities = []string{
+	"required", "important", "standard", "optional", "extra",
+}
+
+var knownAreas = []string{
+	"contrib", "non-free", "non-free-firmware",
+	"main", "restricted", "universe", "multiverse",
+}
+
+var knownSections = []string{
+	"admin", "cli-mono", "comm", "database", "debian-installer", "debug",
+	"devel", "doc", "editors", "education", "electronics", "embedded",
+	"fonts", "games", "gnome", "gnu-r", "gnustep", "graphics", "hamradio",
+	"haskell", "httpd", "interpreters", "introspection", "java",
+	"javascript", "kde", "kernel", "libdevel", "libs", "lisp",
+	"localization", "mail", "math", "metapackages", "misc", "net", "news",
+	"ocaml", "oldlibs", "otherosfs", "perl", "php", "python", "ruby",
+	"rust", "science", "shells", "sound", "tasks", "tex", "text", "utils",
+	"vcs", "video", "web", "x11", "xfce", "zope",
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredFields returns errors for fields without which a package cannot be indexed,
+// it is not a rule, so it cannot be disabled
+func (p *Package) requiredFields() (findings []Finding) {
+	for _, field := range []string{"Package", "Architecture", "Version"} {
+		if p.paragraphs[field] == "" {
+			findings = append(findings, Finding{
+				Rule:     "required-fields",
+				Severity: LintError,
+				Message:  "missing " + field + " from control",
+			})
+		}
+	}
+	return
+}
+
+func lintPackageName(p *Package) []string {
+	if p.Name() != "" && !packageNameRegexp.MatchString(p.Name()) {
+		return []string{fmt.Sprintf("invalid package name: %q", p.Name())}
+	}
+	return nil
+}
+
+func lintVersion(p *Package) []string {
+	if p.Version() == "" {
+		return nil
+	}
+	_, err := ParseVersion(p.Version())
+	if _, warning := err.(versionWarning); err != nil && !warning {
+		return []string{fmt.Sprintf("invalid version %q: %v", p.Version(), err)}
+	}
+	return nil
+}
+
+// lintVersionFormat checks characters of version, that dpkg only warns about
+func lintVersionFormat(p *Package) []string {
+	_, err := ParseVersion(p.Version())
+	if _, warning := err.(versionWarning); warning {
+		return []string{fmt.Sprintf("invalid version %q: %v", p.Version(), err)}
+	}
+	return nil
+}
+
+func lintArchitecture(p *Package) (problems []string) {
+	if p.Type == SourcePackage {
+		return nil
+	}
+	if p.Architecture() != "" && !architectureRegexp.MatchString(p.Architecture()) {
+		problems = append(problems, fmt.Sprintf("invalid architecture: %q", p.Architecture()))
+	}
+	return
+}
+
+// lintArchitectureFileName checks a name_version_arch.deb file name against control
+func lintArchitectureFileName(p *Package) []string {
+	if p.Type == SourcePackage {
+		return nil
+	}
+
+	baseName := p.FileName
+	if idx := strings.LastIndex(baseName, "."); idx >= 0 {
+		baseName = baseName[0:idx]
+	}
+
+	parts := strings.Split(baseName, "_")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	if parts[2] != p.Architecture() {
+		return []string{fmt.Sprintf("architecture %q of file name does not match %q from control",
+			parts[2], p.Architecture())}
+	}
+	return nil
+}
+
+func lintRelationship(field, value string) (problems []string) {
+	for _, relation := range strings.Split(value, ",") {
+		relation = strings.TrimSpace(relation)
+		if relation == "" {
+			problems = append(problems, fmt.Sprintf("%s: empty relation", field))
+			continue
+		}
+
+		for _, alternative := range strings.Split(relation, "|") {
+			alternative = strings.TrimSpace(alternative)
+
+			matches := relationRegexp.FindStringSubmatch(alternative)
+			if matches == nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid relation: %q", field, alternative))
+				continue
+			}
+
+			if matches[5] != "" {
+				_, err := ParseVersion(matches[5])
+				if _, warning := err.(versionWarning); err != nil && !warning {
+					problems = append(problems, fmt.Sprintf("%s: invalid version in %q: %v", field, alternative, err))
+				}
+			}
+		}
+	}
+	return
+}
+
+func lintRelationships(p *Package) (problems []string) {
+	fields := binaryRelationships
+	if p.Type == SourcePackage {
+		fields = sourceRelationships
+	}
+
+	for _, field := range fields {
+		if value, ok := p.paragraphs[field]; ok {
+			problems = append(problems, lintRelationship(field, value)...)
+		}
+	}
+	return
+}
+
+func lintPriority(p *Package) []string {
+	priority := p.paragraphs["Priority"]
+	if priority != "" && !contains(knownPriorities, priority) {
+		return []string{fmt.Sprintf("unknown priority: %q", priority)}
+	}
+	return nil
+}
+
+func lintSection(p *Package) []string {
+	section := p.Section()
+	if section == "" {
+		return nil
+	}
+
+	if parts := strings.SplitN(section, "/", 2); len(parts) == 2 {
+		if !contains(knownAreas, parts[0]) {
+			return []string{fmt.Sprintf("unknown archive area: %q", parts[0])}
+		}
+		section = parts[1]
+	}
+
+	if !contains(knownSections, section) {
+		return []string{fmt.Sprintf("unknown section: %q", section)}
+	}
+	return nil
+}
+
+func lintMaintainer(p *Package) []string {
+	if p.Maintainer() == "" {
+		return []string{"missing Maintainer from control"}
+	}
+	return nil
+}
+
+// Lint returns all problems found in a package
+func (p *Package) Lint() (findings []Finding) {
+	for _, rule := range lintRules {
+		if contains(DisabledLintRules, rule.name) {
+			continue
+		}
+
+		for _, message := range rule.check(p) {
+			findings = append(findings, Finding{
+				Rule:     rule.name,
+				Severity: rule.severity,
+				Message:  message,
+			})
+		}
+	}
+	return
+}
@@ -0,0 +1,132 @@
+package deb
+
+import (
+	"testing"
+)
+
+func testLintPackage(fields map[string]string) *Package {
+	paragraphs := map[string]string{
+		"Package":      "hello",
+		"Version":      "1.0",
+		"Architecture": "amd64",
+		"Maintainer":   "Jane Doe <jane@example.org>",
+	}
+	for field, value := range fields {
+		paragraphs[field] = value
+	}
+	return &Package{
+		Type:       BinaryPackage,
+		FileName:   "hello_1.0_" + paragraphs["Architecture"] + ".deb",
+		paragraphs: paragraphs,
+	}
+}
+
+// lintErrors returns rules of findings that reject a package
+func lintErrors(p *Package) (rules []string) {
+	for _, finding := range append(p.requiredFields(), p.Lint()...) {
+		if finding.Severity == LintError {
+			rules = append(rules, finding.Rule)
+		}
+	}
+	return
+}
+
+func testLintRule(t *testing.T, rule string, cases []struct {
+	fields map[string]string
+	valid  bool
+}) {
+	t.Helper()
+
+	for _, c := range cases {
+		rules := lintErrors(testLintPackage(c.fields))
+		if c.valid && len(rules) > 0 {
+			t.Errorf("%v is rejected by %q", c.fields, rules)
+		} else if !c.valid && !contains(rules, rule) {
+			t.Errorf("%v is not rejected by %s, but %q", c.fields, rule, rules)
+		}
+	}
+}
+
+func TestLintRelationships(t *testing.T) {
+	testLintRule(t, "relationships", []struct {
+		fields map[string]string
+		valid  bool
+	}{
+		{map[string]string{"Depends": "libc6 (>= 2.34), libssl3"}, true},
+		{map[string]string{"Depends": "default-mta | mail-transport-agent"}, true},
+		{map[string]string{"Depends": "python3:any (>= 3.9~)"}, true},
+		{map[string]string{"Depends": "libfoo [amd64 arm64] <!nocheck>"}, true},
+		{map[string]string{"Pre-Depends": "dpkg (>= 1.19.1)", "Provides": "hello-world (= 1.0)"}, true},
+		{map[string]string{"Depends": "${shlibs:Depends}, ${misc:Depends}"}, false},
+		{map[string]string{"Depends": "libc6 (>= 2.34),, libssl3"}, false},
+		{map[string]string{"Depends": "libc6 (=> 2.34)"}, false},
+		{map[string]string{"Depends": "libc6 (>= a:1.0)"}, false},
+		{map[string]string{"Breaks": "Hello (<< 1.0)"}, false},
+	})
+}
+
+func TestLintPackageName(t *testing.T) {
+	testLintRule(t, "package-name", []struct {
+		fields map[string]string
+		valid  bool
+	}{
+		{map[string]string{"Package": "libstdc++6"}, true},
+		{map[string]string{"Package": "python3.11-venv"}, true},
+		{map[string]string{"Package": "Hello"}, false},
+		{map[string]string{"Package": "hello_world"}, false},
+		{map[string]string{"Package": "-hello"}, false},
+		{map[string]string{"Package": "h"}, false},
+	})
+}
+
+func TestLintArchitecture(t *testing.T) {
+	testLintRule(t, "architecture", []struct {
+		fields map[string]string
+		valid  bool
+	}{
+		{map[string]string{"Architecture": "arm64"}, true},
+		{map[string]string{"Architecture": "all"}, true},
+		{map[string]string{"Architecture": "x86_64"}, false},
+		{map[string]string{"Architecture": "AMD64"}, false},
+	})
+}
+
+func TestLintRequiredFields(t *testing.T) {
+	for _, field := range []string{"Package", "Version", "Architecture"} {
+		p := testLintPackage(map[string]string{field: ""})
+		if rules := lintErrors(p); !contains(rules, "required-fields") {
+			t.Errorf("missing %s is not rejected, but %q", field, rules)
+		}
+	}
+
+	// required fields are not a rule, so cannot be disabled
+	DisabledLintRules = []string{"required-fields"}
+	defer func() { DisabledLintRules = nil }()
+
+	if rules := lintErrors(testLintPackage(map[string]string{"Version": ""})); !contains(rules, "required-fields") {
+		t.Errorf("missing Version is not rejected, but %q", rules)
+	}
+}
+
+func TestDisabledLintRules(t *testing.T) {
+	p := testLintPackage(map[string]string{"Depends": "${shlibs:Depends}"})
+
+	DisabledLintRules = []string{"relationships"}
+	defer func() { DisabledLintRules = nil }()
+
+	if rules := lintErrors(p); len(rules) > 0 {
+		t.Errorf("package is rejected by %q", rules)
+	}
+}
+
+func TestValidateLintRules(t *testing.T) {
+	if err := ValidateLintRules([]string{"relationships", "maintainer"}); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateLintRules([]string{"relationship"}); err == nil {
+		t.Error("unknown rule is accepted")
+	}
+	if err := ValidateLintRules([]string{"required-fields"}); err == nil {
+		t.Error("required fields can be disabled")
+	}
+}
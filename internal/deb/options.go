@@ -0,0 +1,126 @@
+package deb
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+// strictUnmarshal decodes options, unknown keys are errors
+func strictUnmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// unmarshalRules decodes a list of rules into a new slice,
+// so rules of a level of config replace, and not reuse the ones of a previous level
+func unmarshalRules[T any](data []byte) ([]T, error) {
+	var rules []T
+	err := strictUnmarshal(data, &rules)
+	return rules, err
+}
+
+// Duration is a time.Duration read from a string, ex. "72h"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// Regexp is a regexp.Regexp read from a string
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	r.Regexp, err = regexp.Compile(value)
+	return err
+}
+
+type SuiteOptions struct {
+	// NotAutomatic makes apt to not install packages from suite, unless requested
+	NotAutomatic bool `json:"not_automatic"`
+
+	// ButAutomaticUpgrades makes apt to upgrade packages installed from suite
+	ButAutomaticUpgrades bool `json:"but_automatic_upgrades"`
+}
+
+type ReleaseOptions struct {
+	Origin  string `json:"origin"`
+	Label   string `json:"label"`
+	Version string `json:"version"`
+
+	// ValidFor makes apt to reject Release older than this
+	ValidFor Duration `json:"valid_for"`
+
+	// ResignEvery moves Valid-Until forward, by default it is half of ValidFor
+	ResignEvery Duration `json:"resign_every"`
+
+	Suites map[string]SuiteOptions `json:"suites"`
+
+	// SeparateArchitectureAll publishes packages of architecture all only in binary-all,
+	// by default they are also in indices of other architectures for clients not supporting it
+	SeparateArchitectureAll bool `json:"separate_architecture_all"`
+}
+
+// RetentionOptions limits versions of each package published in indices,
+// all versions are kept if none is set
+type RetentionOptions struct {
+	// KeepVersions keeps the newest versions of each package
+	KeepVersions int `json:"keep_versions"`
+
+	// KeepNewerThan keeps all versions published recently
+	KeepNewerThan Duration `json:"keep_newer_than"`
+}
+
+// SuiteDiscoveryOptions limits suites discovered from packages
+type SuiteDiscoveryOptions struct {
+	// Allowed lists suites that are published, all are allowed if empty
+	Allowed []string `json:"allowed"`
+
+	// Aliases maps names to suites, ex. "stable": "bookworm"
+	Aliases map[string]string `json:"aliases"`
+}
+
+// IsAllowed returns true if suite can be published
+func (o *SuiteDiscoveryOptions) IsAllowed(suite string) bool {
+	return len(o.Allowed) == 0 || contains(o.Allowed, suite)
+}
+
+// Options are configured globally, per owner and per repository
+type Options struct {
+	Release    ReleaseOptions   `json:"release"`
+	Retention  RetentionOptions `json:"retention"`
+	Components ComponentRules   `json:"components"`
+	SuiteRules SuiteRules       `json:"suite_rules"`
+
+	SuiteDiscovery SuiteDiscoveryOptions `json:"suite_discovery"`
+
+	// SuiteFallbacks lists suites used by suite, when there is no newer build,
+	// ex. "bookworm": ["bullseye"]
+	SuiteFallbacks map[string][]string `json:"suite_fallbacks"`
+}
+
+func DefaultOptions() *Options {
+	return &Options{}
+}
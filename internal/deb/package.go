@@ -15,12 +15,35 @@ import (
 
 	"github.com/google/go-github/github"
 	"github.com/stapelberg/godebiancontrol"
+
+	"github.com/ayufan/debian-repository/internal/helpers"
 )
 
 var Suites = []string{"bionic", "xenial"}
 var Architectures = []string{"arm64", "armhf", "amd64"}
+var Compressors, _ = helpers.FindCompressors([]string{"gz", "xz"})
+
+type PackageType int
+
+const (
+	BinaryPackage PackageType = iota
+	SourcePackage
+	InstallerPackage
+)
+
+func PackageTypeFromFileName(fileName string) PackageType {
+	if strings.HasSuffix(fileName, ".dsc") {
+		return SourcePackage
+	} else if strings.HasSuffix(fileName, ".udeb") {
+		return InstallerPackage
+	}
+	return BinaryPackage
+}
 
+// Key identifies a package, the Version is in canonical form,
+// a .udeb and a .deb of the same name and version are different packages
 type Key struct {
+	Type         PackageType
 	Name         string
 	Version      string
 	Architecture string
@@ -31,24 +54,35 @@ type Package struct {
 
 	paragraphs godebiancontrol.Paragraph
 
+	Type        PackageType
 	RepoName    string
 	TagName     string
-	Suite       string
+	Suites      []string
+	SuiteReason string
 	Component   string
 	FileName    string
 	DownloadURL string
 	FileSize    int
 	UpdatedAt   time.Time
+	ReleaseBody string
+	PublishedAt time.Time
+	Findings    []Finding
 
 	loadOnce   sync.Once
 	loadStatus error
 }
 
 func (p *Package) Key() Key {
+	architecture := p.Architecture()
+	if p.Type == SourcePackage {
+		architecture = "source"
+	}
+
 	return Key{
+		Type:         p.Type,
 		Name:         p.Name(),
-		Version:      p.Version(),
-		Architecture: p.Architecture(),
+		Version:      NormalizeVersion(p.Version()),
+		Architecture: architecture,
 	}
 }
 
@@ -73,28 +107,140 @@ func (p *Package) Version() string {
 	return p.paragraphs["Version"]
 }
 
+func (p *Package) Maintainer() string {
+	if p.paragraphs == nil {
+		return ""
+	}
+	return p.paragraphs["Maintainer"]
+}
+
+// SourceName returns a name of source package, as specified by `Source: name (version)`
+func (p *Package) SourceName() string {
+	if p.paragraphs == nil {
+		return ""
+	}
+	if source := strings.Fields(p.paragraphs["Source"]); len(source) > 0 {
+		return source[0]
+	}
+	return p.Name()
+}
+
+// SourceVersion returns a version of source package, as specified by `Source: name (version)`
+func (p *Package) SourceVersion() string {
+	if p.paragraphs == nil {
+		return ""
+	}
+	if source := strings.Fields(p.paragraphs["Source"]); len(source) > 1 {
+		return strings.Trim(source[1], "()")
+	}
+	return p.Version()
+}
+
+func (p *Package) Section() string {
+	if p.paragraphs == nil {
+		return ""
+	}
+	return p.paragraphs["Section"]
+}
+
+// QualifiedName returns a package name prefixed with a section as used by Contents- indices
+func (p *Package) QualifiedName() string {
+	if p.Section() == "" {
+		return p.Name()
+	}
+	return p.Section() + "/" + p.Name()
+}
+
+// IsDebug returns true for packages with debug symbols,
+// these are published in a separate component
+func (p *Package) IsDebug() bool {
+	if p.Type != BinaryPackage {
+		return false
+	}
+	if strings.HasSuffix(p.FileName, ".ddeb") || strings.HasSuffix(p.Name(), "-dbgsym") {
+		return true
+	}
+	return p.paragraphs != nil && p.paragraphs["Auto-Built-Package"] == "debug-symbols"
+}
+
+// ControlFileNames returns a sorted list of files stored in control.tar
+func (p *Package) ControlFileNames() []string {
+	if p.Archive == nil {
+		return nil
+	}
+	return sortedFileNames(p.ControlFiles)
+}
+
 func (p *Package) MatchingSuite(suite string) bool {
 	if suite != "" {
-		return p.Suite == "all" || p.Suite == suite
+		return contains(p.Suites, allSuites) || contains(p.Suites, suite)
 	}
 
-	return p.Suite == "all"
+	return contains(p.Suites, allSuites)
 }
 
+// MatchingArchitecture returns true if package can be installed on architecture:
+// packages of architecture all match every architecture, and only they match all
 func (p *Package) MatchingArchitecture(architecture string) bool {
-	if architecture != "" {
-		return architecture == "all" || p.Architecture() == architecture
+	switch architecture {
+	case "":
+		return true
+	case allArchitectures:
+		return p.Architecture() == allArchitectures
+	default:
+		return p.Architecture() == architecture || p.Architecture() == allArchitectures
 	}
-
-	return true
 }
 
 func (p *Package) MatchingComponents(component string) bool {
 	return p.Component == component
 }
 
-func (p *Package) Load(release *github.RepositoryRelease, asset *github.ReleaseAsset) error {
-	archive, err := ReadFromURL(*asset.BrowserDownloadURL, "cache-asset-"+strconv.FormatInt(*asset.ID, 10))
+func (p *Package) readArchive(release *github.RepositoryRelease, asset *github.ReleaseAsset) (*Archive, error) {
+	cacheKey := "cache-asset-" + strconv.FormatInt(*asset.ID, 10)
+
+	if p.Type != SourcePackage {
+		return ReadFromURL(*asset.BrowserDownloadURL, cacheKey)
+	}
+
+	archive, err := ReadSourceFromURL(*asset.BrowserDownloadURL, *asset.Name, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// all files of source package have to be attached to the same release
+	paragraphs, err := godebiancontrol.Parse(bytes.NewBuffer(archive.Control))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paragraphs) == 0 {
+		return nil, errors.New("no paragraphs")
+	}
+
+	for _, fileName := range SourceFiles(paragraphs[0]) {
+		found := false
+		for _, releaseAsset := range release.Assets {
+			if releaseAsset.GetName() == fileName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("missing %s of source package", fileName)
+		}
+	}
+	return archive, nil
+}
+
+func (p *Package) Load(release *github.RepositoryRelease, asset *github.ReleaseAsset, options *Options) error {
+	if options == nil {
+		options = DefaultOptions()
+	}
+
+	p.Type = PackageTypeFromFileName(*asset.Name)
+
+	archive, err := p.readArchive(release, asset)
 	if err != nil {
 		return err
 	}
@@ -121,32 +267,22 @@ func (p *Package) Load(release *github.RepositoryRelease, asset *github.ReleaseA
 	p.DownloadURL = *asset.BrowserDownloadURL
 	p.FileSize = *asset.Size
 	p.UpdatedAt = asset.UpdatedAt.Time
-	p.Component = "releases"
-	if release.Prerelease != nil && *release.Prerelease {
-		p.Component = "pre-releases"
-	}
+	p.ReleaseBody = release.GetBody()
+	p.PublishedAt = release.GetPublishedAt().Time
+	p.Component = options.Component(release, asset)
 	p.paragraphs = paragraphs[0]
-
-	for _, suite := range Suites {
-		if strings.Contains(p.Version(), suite) || strings.Contains(p.FileName, suite) {
-			p.Suite = suite
-			break
-		}
+	if p.IsDebug() {
+		p.Component += debugComponentSuffix
 	}
 
-	if p.Suite == "" {
-		p.Suite = "all"
-	}
+	p.Suites, p.SuiteReason = p.detectSuites(release, asset, options)
 
 	// Validate package
-	if p.Name() == "" {
-		return errors.New("missing Package from control")
-	}
-	if p.Architecture() == "" {
-		return errors.New("missing Architecture from control")
-	}
-	if p.Version() == "" {
-		return errors.New("missing Version from control")
+	p.Findings = append(p.requiredFields(), p.Lint()...)
+	for _, finding := range p.Findings {
+		if finding.Severity == LintError {
+			return finding
+		}
 	}
 	return nil
 }
@@ -163,21 +299,35 @@ func (p *Package) scheduleRestart() {
 	}
 }
 
-func (p *Package) Ensure(release *github.RepositoryRelease, asset *github.ReleaseAsset) error {
+func (p *Package) Ensure(release *github.RepositoryRelease, asset *github.ReleaseAsset, options *Options) error {
 	p.loadOnce.Do(func() {
-		p.loadStatus = p.Load(release, asset)
+		p.loadStatus = p.Load(release, asset, options)
 		p.scheduleRestart()
 	})
 	return p.loadStatus
 }
 
-func (p *Package) Write(w io.Writer, organizationWide bool) {
-	w.Write(p.Control)
+func (p *Package) poolDirectory(organizationWide bool) string {
 	if organizationWide {
-		fmt.Fprintln(w, "Filename:", filepath.Join("pool", p.RepoName, p.TagName, p.FileName))
+		return filepath.Join("pool", p.RepoName, p.TagName)
+	}
+	return filepath.Join("pool", p.TagName)
+}
+
+// Write writes a stanza of Packages or Sources,
+// translated packages have only the short description as the long one is in Translation-en
+func (p *Package) Write(w io.Writer, organizationWide, translated bool) {
+	if translated && p.Type == BinaryPackage {
+		control, _ := splitDescription(p.Control)
+		w.Write(control)
+	} else {
+		w.Write(p.Control)
+	}
+	if p.Type == SourcePackage {
+		fmt.Fprintln(w, "Directory:", p.poolDirectory(organizationWide))
 	} else {
-		fmt.Fprintln(w, "Filename:", filepath.Join("pool", p.TagName, p.FileName))
+		fmt.Fprintln(w, "Filename:", filepath.Join(p.poolDirectory(organizationWide), p.FileName))
+		fmt.Fprintln(w, "Size:", p.FileSize)
 	}
-	fmt.Fprintln(w, "Size:", p.FileSize)
 	fmt.Fprintln(w)
 }
@@ -17,9 +17,9 @@ func (a PackageSlice) Less(i, j int) bool {
 		return false
 	}
 
-	if a[i].Version() < a[j].Version() {
+	if result := CompareVersions(a[i].Version(), a[j].Version()); result < 0 {
 		return true
-	} else if a[i].Version() > a[j].Version() {
+	} else if result > 0 {
 		return false
 	}
 
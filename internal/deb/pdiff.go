@@ -0,0 +1,307 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayufan/debian-repository/internal/ed_diff"
+	"github.com/ayufan/debian-repository/internal/helpers"
+	"github.com/ayufan/debian-repository/internal/multi_hash"
+	"github.com/ayufan/debian-repository/internal/repository_cache"
+)
+
+// PdiffHistory is a number of previous generations of Packages
+// for which patches are published, pdiffs are disabled if zero
+var PdiffHistory int
+
+// pdiffMaxEdits limits a number of changed lines in a single patch,
+// files that differ more are not patched, apt downloads them instead
+const pdiffMaxEdits = 2000
+
+const pdiffDir = ".diff"
+const pdiffIndex = "Index"
+
+var pdiffLock sync.Mutex
+
+type pdiffGeneration struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+func pdiffPatchName(from, to pdiffGeneration) string {
+	return "T-" + to.Name + "-F-" + from.Name
+}
+
+func pdiffHistoryTag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "pdiff-" + hex.EncodeToString(sum[:])
+}
+
+// content of generations and patches is kept per history, so it is removed with it
+func pdiffIndexTag(historyTag, sha256 string) string {
+	return historyTag + "-" + sha256
+}
+
+func pdiffPatchTag(historyTag string, from, to pdiffGeneration) string {
+	return historyTag + "-" + from.SHA256 + "-" + to.SHA256
+}
+
+func readPdiffHistory(tag string) []pdiffGeneration {
+	data, err := repository_cache.Read(tag, "history")
+	if err != nil {
+		return nil
+	}
+
+	var history []pdiffGeneration
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Println("Invalid pdiff history", tag, err)
+		return nil
+	}
+	return history
+}
+
+func hasPdiffGeneration(history []pdiffGeneration, sha256 string) bool {
+	for _, generation := range history {
+		if generation.SHA256 == sha256 {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPdiff records a new generation of file when it changes,
+// outdated patches and generations no longer in history are removed
+func (p *Repository) recordPdiff(fileName string, current *multi_hash.MultiHash) error {
+	pdiffLock.Lock()
+	defer pdiffLock.Unlock()
+
+	tag := pdiffHistoryTag(p.byHashKey(fileName))
+	history := readPdiffHistory(tag)
+
+	sha256 := current.Hex("SHA256")
+	if len(history) > 0 && history[len(history)-1].SHA256 == sha256 {
+		return nil
+	}
+
+	err := repository_cache.Write(pdiffIndexTag(tag, sha256), "index", current.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// patches lead to the previous generation
+	if len(history) > 0 {
+		to := history[len(history)-1]
+		for _, from := range history[0 : len(history)-1] {
+			repository_cache.Remove(pdiffPatchTag(tag, from, to), "patch")
+		}
+	}
+
+	history = append(history, pdiffGeneration{
+		Name:   time.Now().UTC().Format("2006-01-02-1504.05"),
+		SHA256: sha256,
+	})
+	if len(history) > PdiffHistory+1 {
+		trimmed := history[0 : len(history)-PdiffHistory-1]
+		history = history[len(history)-PdiffHistory-1:]
+
+		for _, generation := range trimmed {
+			if !hasPdiffGeneration(history, generation.SHA256) {
+				repository_cache.Remove(pdiffIndexTag(tag, generation.SHA256), "index")
+			}
+		}
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	return repository_cache.Write(tag, "history", data)
+}
+
+// pdiffHistory returns generations of file ending with the current one,
+// or nothing if the current one was not recorded
+func (p *Repository) pdiffHistory(fileName string, current *multi_hash.MultiHash) (string, []pdiffGeneration) {
+	pdiffLock.Lock()
+	defer pdiffLock.Unlock()
+
+	tag := pdiffHistoryTag(p.byHashKey(fileName))
+	history := readPdiffHistory(tag)
+	if len(history) == 0 || history[len(history)-1].SHA256 != current.Hex("SHA256") {
+		return tag, nil
+	}
+	return tag, history
+}
+
+func bytesWriter(data []byte) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
+
+// pdiffPatch returns an ed script transforming an old generation into the current one
+func pdiffPatch(tag string, from, to pdiffGeneration, current *multi_hash.MultiHash) ([]byte, error) {
+	patchTag := pdiffPatchTag(tag, from, to)
+	if patch, err := repository_cache.Read(patchTag, "patch"); err == nil {
+		return patch, nil
+	}
+
+	old, err := repository_cache.Read(pdiffIndexTag(tag, from.SHA256), "index")
+	if err != nil {
+		return nil, err
+	}
+
+	var patch bytes.Buffer
+	err = ed_diff.WriteEd(&patch,
+		ed_diff.SplitLines(string(old)),
+		ed_diff.SplitLines(string(current.Bytes())),
+		pdiffMaxEdits)
+	if err != nil {
+		return nil, err
+	}
+
+	repository_cache.Write(patchTag, "patch", patch.Bytes())
+	return patch.Bytes(), nil
+}
+
+// WritePdiffIndex writes Packages.diff/Index with merged patches
+// from all previous generations to the current one
+func (p *Repository) WritePdiffIndex(w io.Writer, fileName string, writer func(io.Writer) error) error {
+	current, err := multi_hash.HashMe(writer)
+	if err != nil {
+		return err
+	}
+
+	// patches are listed only if the current generation was recorded
+	tag, history := p.pdiffHistory(fileName, current)
+
+	var names []string
+	var olds, patches, downloads []*multi_hash.MultiHash
+
+	for idx := 0; idx+1 < len(history); idx++ {
+		from, to := history[idx], history[len(history)-1]
+
+		patch, err := pdiffPatch(tag, from, to, current)
+		if err != nil {
+			log.Println("Skipping pdiff of", fileName, "from", from.Name, err)
+			continue
+		}
+
+		data, err := repository_cache.Read(pdiffIndexTag(tag, from.SHA256), "index")
+		if err != nil {
+			continue
+		}
+
+		old, _ := multi_hash.HashMe(bytesWriter(data))
+		patchHash, _ := multi_hash.HashMe(bytesWriter(patch))
+		downloadHash, err := multi_hash.HashMe(helpers.GzWriter(bytesWriter(patch)))
+		if err != nil {
+			return err
+		}
+
+		names = append(names, pdiffPatchName(from, to))
+		olds = append(olds, old)
+		patches = append(patches, patchHash)
+		downloads = append(downloads, downloadHash)
+	}
+
+	for _, hashName := range []string{"SHA1", "SHA256"} {
+		fmt.Fprintln(w, hashName+"-Current:", current.Hex(hashName), len(current.Bytes()))
+	}
+
+	sections := []struct {
+		name   string
+		hashes []*multi_hash.MultiHash
+		suffix string
+	}{
+		{"History", olds, ""},
+		{"Patches", patches, ""},
+		{"Download", downloads, ".gz"},
+	}
+
+	for _, section := range sections {
+		for _, hashName := range []string{"SHA1", "SHA256"} {
+			fmt.Fprint(w, hashName, "-", section.name, ":\n")
+			for idx, hash := range section.hashes {
+				hash.WriteReleaseHash(w, hashName, names[idx]+section.suffix)
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "X-Patch-Precedence:", "merged")
+	return nil
+}
+
+// addPdiff publishes Packages.diff/Index next to the index,
+// only for known suites, as generations are recorded on disk
+func (p *Repository) addPdiff(files map[string]*RepositoryFile, fileName string) {
+	if PdiffHistory <= 0 || !p.isKnown() {
+		return
+	}
+
+	fileOpt := files[fileName]
+	if fileOpt == nil {
+		return
+	}
+
+	files[path.Join(fileName+pdiffDir, pdiffIndex)] = &RepositoryFile{
+		Writer: func(w io.Writer) error {
+			return p.WritePdiffIndex(w, fileName, fileOpt.Writer)
+		},
+		uncompressed: true,
+	}
+}
+
+// PdiffFile returns a patch listed in Packages.diff/Index
+func (p *Repository) PdiffFile(fileName string) (*RepositoryFile, error) {
+	dir, patchName := path.Split(fileName)
+	dir = path.Clean(dir)
+	if PdiffHistory <= 0 || !strings.HasSuffix(dir, pdiffDir) {
+		return nil, nil
+	}
+
+	indexName := strings.TrimSuffix(dir, pdiffDir)
+	fileOpt := p.Files()[indexName]
+	if fileOpt == nil {
+		return nil, nil
+	}
+
+	current, err := fileOpt.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	tag, history := p.pdiffHistory(indexName, current)
+
+	for idx := 0; idx+1 < len(history); idx++ {
+		from, to := history[idx], history[len(history)-1]
+
+		name := pdiffPatchName(from, to)
+		if patchName != name && patchName != name+".gz" {
+			continue
+		}
+
+		patch, err := pdiffPatch(tag, from, to, current)
+		if err != nil {
+			return nil, err
+		}
+
+		writer := bytesWriter(patch)
+		if patchName != name {
+			writer = helpers.GzWriter(writer)
+		}
+		return &RepositoryFile{Writer: writer}, nil
+	}
+
+	return nil, nil
+}
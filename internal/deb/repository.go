@@ -15,21 +15,34 @@ import (
 
 type Repository struct {
 	debs             PackageSlice
-	loaded           map[Key]struct{}
+	frozen           map[string]*RepositoryFile
+	validUntil       time.Time // of a frozen Release
+	frozenSize       int64
+	loaded           map[Key]*Package
+	priorities       map[*Package]int
+	discovered       map[string]struct{}
 	owner, repo      string
 	suite, component string
+	suiteAlias       string
+	url              string
 	organizationWide bool
 	signingKey       *deb_key.Key
+	options          *Options
 }
 
 type RepositoryFile struct {
 	Writer func(io.Writer) error
 
-	hash *multi_hash.MultiHash
+	hash         *multi_hash.MultiHash
+	uncompressed bool
 }
 
+const allArchitectures = "all"
+
+// Architectures returns architectures of indices, including all
 func (p *Repository) Architectures() map[string]struct{} {
 	archs := make(map[string]struct{})
+	archs[allArchitectures] = struct{}{}
 
 	// get a default architectures
 	for _, arch := range Architectures {
@@ -38,7 +51,7 @@ func (p *Repository) Architectures() map[string]struct{} {
 
 	// get all other architectures
 	for _, deb := range p.debs {
-		if deb.Architecture() == "all" {
+		if deb.Type == SourcePackage {
 			continue
 		}
 		archs[deb.Architecture()] = struct{}{}
@@ -46,64 +59,384 @@ func (p *Repository) Architectures() map[string]struct{} {
 	return archs
 }
 
+// matchingArchitecture returns true if package is published in index of architecture,
+// packages of architecture all are in binary-all, and also in other indices unless separated
+func (p *Repository) matchingArchitecture(deb *Package, architecture string) bool {
+	if p.options.Release.SeparateArchitectureAll && deb.Architecture() == allArchitectures &&
+		architecture != "" && architecture != allArchitectures {
+		return false
+	}
+	return deb.MatchingArchitecture(architecture)
+}
+
 func (p *Repository) Add(debPackage *Package) error {
-	if !debPackage.MatchingSuite(p.suite) {
-		return nil
+	if p.discovered == nil {
+		p.discovered = make(map[string]struct{})
+	}
+	for _, suite := range debPackage.Suites {
+		if suite != allSuites {
+			p.discovered[suite] = struct{}{}
+		}
 	}
 
-	// don't add the same version, again
-	if _, ok := p.loaded[debPackage.Key()]; ok {
-		log.Println("ignore", debPackage.Key())
+	priority := p.suitePriority(debPackage)
+	if priority < 0 {
 		return nil
 	}
 
 	if p.loaded == nil {
-		p.loaded = make(map[Key]struct{})
+		p.loaded = make(map[Key]*Package)
+		p.priorities = make(map[*Package]int)
+	}
+
+	// don't add the same version (as compared by dpkg), again,
+	// unless it is built for suite closer than the added one
+	if loaded := p.loaded[debPackage.Key()]; loaded != nil {
+		if priority >= p.priorities[loaded] {
+			log.Println("ignore", debPackage.Key())
+			return nil
+		}
+
+		for idx, deb := range p.debs {
+			if deb == loaded {
+				p.debs[idx] = debPackage
+			}
+		}
+		delete(p.priorities, loaded)
+	} else {
+		p.debs = append(p.debs, debPackage)
 	}
-	p.loaded[debPackage.Key()] = struct{}{}
-	p.debs = append(p.debs, debPackage)
+
+	p.loaded[debPackage.Key()] = debPackage
+	p.priorities[debPackage] = priority
 	return nil
 }
 
+// suitePriority returns 0 for packages of suite, a position of fallback suite,
+// or -1 if package is not published in suite
+func (p *Repository) suitePriority(debPackage *Package) int {
+	if debPackage.MatchingSuite(p.suite) {
+		return 0
+	}
+	if p.suite == "" {
+		return -1
+	}
+
+	for idx, suite := range p.options.fallbackSuites(p.suite) {
+		if contains(debPackage.Suites, suite) {
+			return idx + 1
+		}
+	}
+	return -1
+}
+
+// Suites returns allowed suites: the configured ones, discovered from packages,
+// ones with fallbacks, and aliases
+func (p *Repository) Suites() []string {
+	discovery := p.options.SuiteDiscovery
+
+	var suites []string
+	for _, suite := range Suites {
+		if suite != "" && discovery.IsAllowed(suite) && !contains(suites, suite) {
+			suites = append(suites, suite)
+		}
+	}
+
+	var discovered []string
+	for suite := range p.discovered {
+		if discovery.IsAllowed(suite) && !contains(suites, suite) {
+			discovered = append(discovered, suite)
+		}
+	}
+	sort.Strings(discovered)
+	suites = append(suites, discovered...)
+
+	// suites that use packages of others
+	var fallbacks []string
+	for suite := range p.options.SuiteFallbacks {
+		if discovery.IsAllowed(suite) && !contains(suites, suite) {
+			fallbacks = append(fallbacks, suite)
+		}
+	}
+	sort.Strings(fallbacks)
+	suites = append(suites, fallbacks...)
+
+	var aliases []string
+	for alias, suite := range discovery.Aliases {
+		if contains(suites, suite) && !contains(suites, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return append(suites, aliases...)
+}
+
+// isKnown returns true if repository is served for one of Suites, or for a used component
+// of flat repository, only files of known repositories are kept on disk
+func (p *Repository) isKnown() bool {
+	if p.suite != "" {
+		return contains(p.Suites(), p.suiteName())
+	}
+	return contains(p.Components(), p.component)
+}
+
 func (p *Repository) Sort() {
 	sort.Sort(p.debs)
 }
 
+// Components returns a list of components published in suite:
+// configured ones, their debug variants used by packages, and all others used by packages
+func (p *Repository) Components() []string {
+	components := p.options.ComponentNames()
+	for _, component := range p.options.ComponentNames() {
+		if p.hasComponent(component + debugComponentSuffix) {
+			components = append(components, component+debugComponentSuffix)
+		}
+	}
+
+	var others []string
+	for _, deb := range p.debs {
+		if !contains(components, deb.Component) && !contains(others, deb.Component) {
+			others = append(others, deb.Component)
+		}
+	}
+	sort.Strings(others)
+
+	return append(components, others...)
+}
+
+func (p *Repository) hasComponent(component string) bool {
+	for _, deb := range p.debs {
+		if deb.Component == component {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Repository) hasInstallerPackages(component string) bool {
+	for _, deb := range p.debs {
+		if deb.Type == InstallerPackage && deb.MatchingComponents(component) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Repository) writePackages(w io.Writer, packageType PackageType, component, architecture string) error {
+	for _, deb := range p.debs {
+		if deb.Type != packageType {
+			continue
+		}
+		if !p.matchingArchitecture(deb, architecture) {
+			continue
+		}
+		if !deb.MatchingComponents(component) {
+			continue
+		}
+
+		deb.Write(w, p.organizationWide, p.suite != "")
+	}
+	return nil
+}
+
 func (p *Repository) WritePackages(w io.Writer, component, architecture string) error {
+	return p.writePackages(w, BinaryPackage, component, architecture)
+}
+
+// WriteInstallerPackages writes .udeb packages used by debian-installer
+func (p *Repository) WriteInstallerPackages(w io.Writer, component, architecture string) error {
+	return p.writePackages(w, InstallerPackage, component, architecture)
+}
+
+func (p *Repository) WriteSources(w io.Writer, component string) error {
 	for _, deb := range p.debs {
-		if !deb.MatchingArchitecture(architecture) {
+		if deb.Type != SourcePackage {
 			continue
 		}
 		if !deb.MatchingComponents(component) {
 			continue
 		}
 
-		deb.Write(w, p.organizationWide)
+		deb.Write(w, p.organizationWide, false)
 	}
 	return nil
 }
 
-func (p *Repository) Files() map[string]*RepositoryFile {
-	files := make(map[string]*RepositoryFile)
+// WriteTranslation writes the long descriptions of all packages in component
+func (p *Repository) WriteTranslation(w io.Writer, component string) error {
+	written := make(map[string]struct{})
 
-	if p.suite != "" {
-		for arch := range p.Architectures() {
-			if arch == "" {
+	for _, deb := range p.debs {
+		if deb.Type != BinaryPackage {
+			continue
+		}
+		if !deb.MatchingComponents(component) {
+			continue
+		}
+
+		_, description := splitDescription(deb.Control)
+		key := deb.Name() + "/" + DescriptionMd5(description)
+		if _, ok := written[key]; ok {
+			continue
+		}
+
+		if deb.WriteTranslation(w) {
+			written[key] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// WriteTranslationIndex writes i18n/Index with hashes of all Translation- files
+func (p *Repository) WriteTranslationIndex(w io.Writer, files map[string]*RepositoryFile, dir string) error {
+	var fileNames []string
+	for fileName := range files {
+		if strings.HasPrefix(fileName, dir+"/Translation-") {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	sort.Strings(fileNames)
+
+	fmt.Fprintln(w, "SHA1:")
+	for _, fileName := range fileNames {
+		hash, err := files[fileName].Hash()
+		if err != nil {
+			return err
+		}
+		hash.WriteReleaseHash(w, "SHA1", strings.TrimPrefix(fileName, dir+"/"))
+	}
+	return nil
+}
+
+func (p *Repository) WriteContents(w io.Writer, component, architecture string) error {
+	contents := make(map[string][]string)
+
+	for _, deb := range p.debs {
+		if deb.Type != BinaryPackage {
+			continue
+		}
+		if !p.matchingArchitecture(deb, architecture) {
+			continue
+		}
+		if !deb.MatchingComponents(component) {
+			continue
+		}
+
+		qualifiedName := deb.QualifiedName()
+
+		for _, fileName := range deb.Files {
+			locations := contents[fileName]
+			if len(locations) > 0 && locations[len(locations)-1] == qualifiedName {
 				continue
 			}
+			contents[fileName] = append(locations, qualifiedName)
+		}
+	}
 
-			arch_ := arch
+	fileNames := make([]string, 0, len(contents))
+	for fileName := range contents {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		_, err := fmt.Fprintf(w, "%s\t%s\n", fileName, strings.Join(contents[fileName], ","))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Repository) Files() map[string]*RepositoryFile {
+	if p.frozen != nil {
+		return p.frozenFiles(false)
+	}
+
+	files := make(map[string]*RepositoryFile)
 
-			files["releases/binary-"+arch+"/Packages"] = &RepositoryFile{
+	if p.suite != "" {
+		for _, component := range p.Components() {
+			component_ := component
+
+			files[component+"/source/Sources"] = &RepositoryFile{
 				Writer: func(w io.Writer) error {
-					return p.WritePackages(w, "releases", arch_)
+					return p.WriteSources(w, component_)
 				},
 			}
-			files["pre-releases/binary-"+arch+"/Packages"] = &RepositoryFile{
+			files[component+"/i18n/Translation-en"] = &RepositoryFile{
 				Writer: func(w io.Writer) error {
-					return p.WritePackages(w, "pre-releases", arch_)
+					return p.WriteTranslation(w, component_)
 				},
 			}
+			files[component+"/source/Release"] = &RepositoryFile{
+				Writer: func(w io.Writer) error {
+					return p.WriteComponentRelease(w, component_, "source")
+				},
+				uncompressed: true,
+			}
+			files[component+"/i18n/Index"] = &RepositoryFile{
+				Writer: func(w io.Writer) error {
+					return p.WriteTranslationIndex(w, files, component_+"/i18n")
+				},
+				uncompressed: true,
+			}
+
+			hasAppStream := p.hasAppStream(component)
+			hasInstallerPackages := p.hasInstallerPackages(component)
+			if hasAppStream {
+				for _, size := range AppStreamIconSizes {
+					size_ := size
+
+					files[component+"/dep11/icons-"+size+".tar"] = &RepositoryFile{
+						Writer: func(w io.Writer) error {
+							return p.WriteAppStreamIcons(w, component_, size_)
+						},
+					}
+				}
+			}
+
+			for arch := range p.Architectures() {
+				if arch == "" {
+					continue
+				}
+
+				arch_ := arch
+
+				// AppStream of architecture all is in every Components-
+				if hasAppStream && arch != allArchitectures {
+					files[component+"/dep11/Components-"+arch+".yml"] = &RepositoryFile{
+						Writer: func(w io.Writer) error {
+							return p.WriteAppStream(w, component_, arch_)
+						},
+					}
+				}
+				files[component+"/binary-"+arch+"/Packages"] = &RepositoryFile{
+					Writer: func(w io.Writer) error {
+						return p.WritePackages(w, component_, arch_)
+					},
+				}
+				p.addPdiff(files, component+"/binary-"+arch+"/Packages")
+				files[component+"/binary-"+arch+"/Release"] = &RepositoryFile{
+					Writer: func(w io.Writer) error {
+						return p.WriteComponentRelease(w, component_, arch_)
+					},
+					uncompressed: true,
+				}
+				if hasInstallerPackages {
+					files[component+"/debian-installer/binary-"+arch+"/Packages"] = &RepositoryFile{
+						Writer: func(w io.Writer) error {
+							return p.WriteInstallerPackages(w, component_, arch_)
+						},
+					}
+				}
+				files[component+"/Contents-"+arch] = &RepositoryFile{
+					Writer: func(w io.Writer) error {
+						return p.WriteContents(w, component_, arch_)
+					},
+				}
+			}
 		}
 	} else {
 		files["Packages"] = &RepositoryFile{
@@ -111,16 +444,43 @@ func (p *Repository) Files() map[string]*RepositoryFile {
 				return p.WritePackages(w, p.component, "")
 			},
 		}
+		p.addPdiff(files, "Packages")
+		files["Sources"] = &RepositoryFile{
+			Writer: func(w io.Writer) error {
+				return p.WriteSources(w, p.component)
+			},
+		}
+
+		for arch := range p.Architectures() {
+			if arch == "" {
+				continue
+			}
+
+			arch_ := arch
+
+			files["Contents-"+arch] = &RepositoryFile{
+				Writer: func(w io.Writer) error {
+					return p.WriteContents(w, p.component, arch_)
+				},
+			}
+		}
 	}
 
-	// compress all files
+	// compress all files, a writer is read when compressing,
+	// so compressed files use the rendered one once frozen
 	for fileName, fileOpt := range files {
-		if strings.HasSuffix(fileName, ".gz") {
+		if fileOpt.uncompressed || helpers.IsCompressed(fileName) {
 			continue
 		}
 
-		files[fileName+".gz"] = &RepositoryFile{
-			Writer: helpers.GzWriter(fileOpt.Writer),
+		fileOpt_ := fileOpt
+
+		for _, compressor := range Compressors {
+			files[fileName+compressor.Extension] = &RepositoryFile{
+				Writer: compressor.Writer(func(w io.Writer) error {
+					return fileOpt_.Writer(w)
+				}),
+			}
 		}
 	}
 
@@ -128,19 +488,19 @@ func (p *Repository) Files() map[string]*RepositoryFile {
 }
 
 func (p *Repository) AllFiles() map[string]*RepositoryFile {
+	if p.frozen != nil {
+		return p.frozenFiles(true)
+	}
+
 	files := p.Files()
 	files["Release"] = &RepositoryFile{
 		Writer: p.WriteRelease,
 	}
 	files["Release.gpg"] = &RepositoryFile{
-		Writer: func(w io.Writer) error {
-			return p.signingKey.EncodeWithArmor(w, p.WriteRelease)
-		},
+		Writer: p.signedWriter("Release.gpg"),
 	}
 	files["InRelease"] = &RepositoryFile{
-		Writer: func(w io.Writer) error {
-			return p.signingKey.Encode(w, p.WriteRelease)
-		},
+		Writer: p.signedWriter("InRelease"),
 	}
 	return files
 }
@@ -154,7 +514,16 @@ func (p *Repository) newestUpdatedAt() (result time.Time) {
 	return
 }
 
+// UpdatedAt returns the newest upload of packages, a Date of Release
+func (p *Repository) UpdatedAt() time.Time {
+	return p.newestUpdatedAt()
+}
+
 func (p *Repository) getOrigin() string {
+	if p.options.Release.Origin != "" {
+		return p.options.Release.Origin
+	}
+
 	components := []string{
 		"GITHUB", "AYUFAN", "DEB",
 	}
@@ -167,6 +536,40 @@ func (p *Repository) getOrigin() string {
 	return strings.Join(components, "-")
 }
 
+func (p *Repository) getLabel() string {
+	if p.options.Release.Label != "" {
+		return p.options.Release.Label
+	}
+	return p.getOrigin()
+}
+
+// getValidUntil returns a time when Release expires,
+// it is moved forward every ResignEvery, so Release is stable between
+func (p *Repository) getValidUntil() time.Time {
+	validFor := time.Duration(p.options.Release.ValidFor)
+	if validFor <= 0 {
+		return time.Time{}
+	}
+
+	resignEvery := time.Duration(p.options.Release.ResignEvery)
+	if resignEvery <= 0 || resignEvery > validFor {
+		resignEvery = validFor / 2
+	}
+
+	return time.Now().Truncate(resignEvery).Add(validFor)
+}
+
+func (p *Repository) getArchitectures() []string {
+	var architectures []string
+	for arch := range p.Architectures() {
+		if arch != "" {
+			architectures = append(architectures, arch)
+		}
+	}
+	sort.Strings(architectures)
+	return architectures
+}
+
 func (p *Repository) getDescription() string {
 	components := []string{
 		"https://github.com",
@@ -180,41 +583,110 @@ func (p *Repository) getDescription() string {
 	return strings.Join(components, "/")
 }
 
+// suiteName returns an alias under which suite is served, or the suite
+func (p *Repository) suiteName() string {
+	if p.suiteAlias != "" {
+		return p.suiteAlias
+	}
+	return p.suite
+}
+
+// WriteComponentRelease writes Release of a single component and architecture,
+// as used by mirroring tools
+func (p *Repository) WriteComponentRelease(w io.Writer, component, architecture string) error {
+	fmt.Fprintln(w, "Archive:", p.suiteName())
+	fmt.Fprintln(w, "Origin:", p.getOrigin())
+	fmt.Fprintln(w, "Label:", p.getLabel())
+	if p.options.Release.Version != "" {
+		fmt.Fprintln(w, "Version:", p.options.Release.Version)
+	}
+	fmt.Fprintln(w, "Acquire-By-Hash:", "yes")
+	fmt.Fprintln(w, "Component:", component)
+	fmt.Fprintln(w, "Architecture:", architecture)
+	return nil
+}
+
 func (p *Repository) WriteRelease(w io.Writer) error {
 	files := p.Files()
 
-	for _, fileOpt := range files {
-		hash, err := multi_hash.HashMe(fileOpt.Writer)
+	// files are sorted, so the same content gives the same Release
+	fileNames := make([]string, 0, len(files))
+	for fileName, fileOpt := range files {
+		hash, err := fileOpt.Hash()
 		if err != nil {
 			return err
 		}
 		fileOpt.hash = hash
+		p.storeByHash(fileName, hash)
+		fileNames = append(fileNames, fileName)
 	}
+	sort.Strings(fileNames)
 
 	fmt.Fprintln(w, "Origin:", p.getOrigin())
-	fmt.Fprintln(w, "Description:", p.getDescription())
-	fmt.Fprintln(w, "Date:", p.newestUpdatedAt().Format(time.RFC1123))
+	fmt.Fprintln(w, "Label:", p.getLabel())
+	if p.options.Release.Version != "" {
+		fmt.Fprintln(w, "Version:", p.options.Release.Version)
+	}
+	fmt.Fprintln(w, "Date:", p.newestUpdatedAt().UTC().Format(time.RFC1123))
+	validUntil := p.validUntil
+	if validUntil.IsZero() {
+		validUntil = p.getValidUntil()
+	}
+	if !validUntil.IsZero() {
+		fmt.Fprintln(w, "Valid-Until:", validUntil.UTC().Format(time.RFC1123))
+	}
 	if p.suite != "" {
+		suiteOptions := p.options.Release.Suites[p.suite]
+
+		fmt.Fprintln(w, "Suite:", p.suiteName())
 		fmt.Fprintln(w, "Codename:", p.suite)
-		fmt.Fprintln(w, "Components:", "releases pre-releases")
+		if suiteOptions.NotAutomatic {
+			fmt.Fprintln(w, "NotAutomatic:", "yes")
+		}
+		if suiteOptions.ButAutomaticUpgrades {
+			fmt.Fprintln(w, "ButAutomaticUpgrades:", "yes")
+		}
+		fmt.Fprintln(w, "Acquire-By-Hash:", "yes")
+		if !p.options.Release.SeparateArchitectureAll {
+			fmt.Fprintln(w, "No-Support-for-Architecture-all:", "Packages")
+		}
+		fmt.Fprintln(w, "Architectures:", strings.Join(p.getArchitectures(), " "))
+		fmt.Fprintln(w, "Components:", strings.Join(p.Components(), " "))
+		if p.url != "" {
+			fmt.Fprintln(w, "Changelogs:", p.url+"/changelogs/@CHANGEPATH@_changelog")
+		}
 	}
+	fmt.Fprintln(w, "Description:", p.getDescription())
 	for _, hashOpt := range multi_hash.Hashes {
 		fmt.Fprint(w, hashOpt.Name, ":\n")
-		for fileName, fileOpt := range files {
-			fileOpt.hash.WriteReleaseHash(w, hashOpt.Name, fileName)
+		for _, fileName := range fileNames {
+			files[fileName].hash.WriteReleaseHash(w, hashOpt.Name, fileName)
 		}
 	}
 
 	return nil
 }
 
-func NewRepository(owner, repo, suite, component string, signingKey *deb_key.Key) *Repository {
+func NewRepository(owner, repo, suite, component, url string, signingKey *deb_key.Key, options *Options) *Repository {
+	if options == nil {
+		options = DefaultOptions()
+	}
+
+	// serve aliased suite, ex. stable as bookworm
+	var suiteAlias string
+	if aliased := options.SuiteDiscovery.Aliases[suite]; aliased != "" {
+		suiteAlias, suite = suite, aliased
+	}
+
 	return &Repository{
 		owner:            owner,
 		repo:             repo,
 		suite:            suite,
+		suiteAlias:       suiteAlias,
 		component:        component,
+		url:              url,
 		organizationWide: repo == "",
 		signingKey:       signingKey,
+		options:          options,
 	}
 }
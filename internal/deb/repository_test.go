@@ -0,0 +1,112 @@
+package deb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	debug := testPackage("hello-dbgsym", "1.0", "bookworm")
+	debug.Component = defaultComponent + debugComponentSuffix
+	nightly := testPackage("hello", "1.1", "bookworm")
+	nightly.Component = "nightly"
+
+	repository := NewRepository("owner", "repo", "bookworm", "", "", nil, nil)
+	if components := repository.Components(); !reflect.DeepEqual(components, []string{"releases", "pre-releases"}) {
+		t.Errorf("Components() = %q, debug components without packages are listed", components)
+	}
+
+	repository.Add(testPackage("hello", "1.0", "bookworm"))
+	repository.Add(debug)
+	repository.Add(nightly)
+
+	expected := []string{"releases", "pre-releases", "releases-debug", "nightly"}
+	if components := repository.Components(); !reflect.DeepEqual(components, expected) {
+		t.Errorf("Components() = %q, expected %q", components, expected)
+	}
+}
+
+func TestInstallerPackages(t *testing.T) {
+	deb := testPackage("hello", "1.0", "bookworm")
+	deb.Archive = &Archive{}
+	repository := NewRepository("owner", "repo", "bookworm", "", "", nil, nil)
+	repository.Add(deb)
+
+	if files := repository.Files(); files["releases/debian-installer/binary-amd64/Packages"] != nil {
+		t.Error("debian-installer index is listed without .udeb packages")
+	}
+
+	// a .udeb of the same name and version is not ignored
+	udeb := testPackage("hello", "1.0", "bookworm")
+	udeb.Archive = &Archive{}
+	udeb.Type = InstallerPackage
+	repository.Add(udeb)
+
+	if len(repository.debs) != 2 {
+		t.Fatalf("%d packages are added, expected .deb and .udeb", len(repository.debs))
+	}
+	files := repository.Files()
+	if files["releases/debian-installer/binary-amd64/Packages"] == nil {
+		t.Error("debian-installer index of .udeb packages is not listed")
+	}
+	if files["pre-releases/debian-installer/binary-amd64/Packages"] != nil {
+		t.Error("debian-installer index is listed for component without .udeb packages")
+	}
+}
+
+func TestMatchingArchitecture(t *testing.T) {
+	cases := []struct {
+		separate     bool
+		packageArch  string
+		architecture string
+		expected     bool
+	}{
+		{false, "amd64", "", true},
+		{false, "all", "", true},
+		{false, "amd64", "all", false},
+		{false, "all", "all", true},
+		{false, "amd64", "amd64", true},
+		{false, "arm64", "amd64", false},
+		{false, "all", "amd64", true},
+		{true, "all", "", true},
+		{true, "all", "all", true},
+		{true, "amd64", "amd64", true},
+		{true, "all", "amd64", false},
+	}
+
+	for _, c := range cases {
+		options := DefaultOptions()
+		options.Release.SeparateArchitectureAll = c.separate
+		repository := NewRepository("owner", "repo", "bookworm", "", "", nil, options)
+
+		deb := testPackage("hello", "1.0", "bookworm")
+		deb.paragraphs["Architecture"] = c.packageArch
+		if matching := repository.matchingArchitecture(deb, c.architecture); matching != c.expected {
+			t.Errorf("separate=%v: package of %q matches %q = %v, expected %v",
+				c.separate, c.packageArch, c.architecture, matching, c.expected)
+		}
+	}
+}
+
+func TestNoSupportForArchitectureAll(t *testing.T) {
+	for _, separate := range []bool{false, true} {
+		options := DefaultOptions()
+		options.Release.SeparateArchitectureAll = separate
+		repository := NewRepository("owner", "repo", "bookworm", "", "", nil, options)
+
+		deb := testPackage("hello", "1.0", "bookworm")
+		deb.Archive = &Archive{}
+		deb.paragraphs["Architecture"] = "all"
+		repository.Add(deb)
+
+		var release bytes.Buffer
+		if err := repository.WriteRelease(&release); err != nil {
+			t.Fatal(err)
+		}
+		if listed := strings.Contains(release.String(), "\nNo-Support-for-Architecture-all: Packages\n"); listed == separate {
+			t.Errorf("separate=%v: No-Support-for-Architecture-all is listed = %v", separate, listed)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package deb
+
+import (
+	"time"
+)
+
+// versionsKey groups versions of the same package in repository of a single suite,
+// components are separate, so pre-releases do not push out releases
+type versionsKey struct {
+	packageType  PackageType
+	name         string
+	architecture string
+	component    string
+}
+
+func (p *Package) versionsKey() versionsKey {
+	return versionsKey{p.Type, p.Name(), p.Architecture(), p.Component}
+}
+
+func (p *Package) publishedAt() time.Time {
+	if p.PublishedAt.IsZero() {
+		return p.UpdatedAt
+	}
+	return p.PublishedAt
+}
+
+// pruneFallbacks removes builds of fallback suites
+// that are not newer than the newest build of suite,
+// the rest is taken from the first fallback in order of preference
+func (p *Repository) pruneFallbacks() {
+	newest := make(map[versionsKey]string)
+	for _, deb := range p.debs {
+		if p.priorities[deb] != 0 {
+			continue
+		}
+
+		key := deb.versionsKey()
+		if version, ok := newest[key]; !ok || CompareVersions(deb.Version(), version) > 0 {
+			newest[key] = deb.Version()
+		}
+	}
+
+	newer := func(deb *Package) bool {
+		version, ok := newest[deb.versionsKey()]
+		return !ok || CompareVersions(deb.Version(), version) > 0
+	}
+
+	best := make(map[versionsKey]int)
+	for _, deb := range p.debs {
+		priority := p.priorities[deb]
+		if priority == 0 || !newer(deb) {
+			continue
+		}
+
+		key := deb.versionsKey()
+		if bestPriority, ok := best[key]; !ok || priority < bestPriority {
+			best[key] = priority
+		}
+	}
+
+	debs := make(PackageSlice, 0, len(p.debs))
+	for _, deb := range p.debs {
+		priority := p.priorities[deb]
+		if priority != 0 && (!newer(deb) || priority != best[deb.versionsKey()]) {
+			continue
+		}
+		debs = append(debs, deb)
+	}
+	p.debs = debs
+}
+
+// Prune removes builds of fallback suites replaced by builds of a preferred suite,
+// and versions not matching retention policy from indices,
+// the newest version is always kept. Packages have to be sorted.
+func (p *Repository) Prune() {
+	p.pruneFallbacks()
+
+	retention := p.options.Retention
+	if retention.KeepVersions <= 0 && retention.KeepNewerThan <= 0 {
+		return
+	}
+
+	newerThan := time.Now().Add(-time.Duration(retention.KeepNewerThan))
+	counts := make(map[versionsKey]int)
+	debs := make(PackageSlice, 0, len(p.debs))
+
+	// iterate from the newest version
+	for i := len(p.debs) - 1; i >= 0; i-- {
+		deb := p.debs[i]
+		count := counts[deb.versionsKey()]
+		counts[deb.versionsKey()]++
+
+		switch {
+		case count == 0:
+		case retention.KeepVersions > 0 && count < retention.KeepVersions:
+		case retention.KeepNewerThan > 0 && deb.publishedAt().After(newerThan):
+		default:
+			continue
+		}
+
+		debs = append(debs, deb)
+	}
+
+	// restore the order
+	for i, j := 0, len(debs)-1; i < j; i, j = i+1, j-1 {
+		debs[i], debs[j] = debs[j], debs[i]
+	}
+	p.debs = debs
+}
@@ -0,0 +1,204 @@
+package deb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testPackage(name, version string, suites ...string) *Package {
+	return &Package{
+		Type:      BinaryPackage,
+		Component: defaultComponent,
+		Suites:    suites,
+		paragraphs: map[string]string{
+			"Package":      name,
+			"Version":      version,
+			"Architecture": "amd64",
+		},
+	}
+}
+
+func testRepository(options *Options, suite string, debs ...*Package) []string {
+	repository := NewRepository("owner", "repo", suite, "", "", nil, options)
+	for _, deb := range debs {
+		repository.Add(deb)
+	}
+	repository.Sort()
+	repository.Prune()
+
+	var published []string
+	for _, deb := range repository.debs {
+		published = append(published, deb.Name()+"="+deb.Version()+"/"+deb.Suites[0])
+	}
+	return published
+}
+
+func TestPruneFallbacks(t *testing.T) {
+	options := &Options{
+		SuiteFallbacks: map[string][]string{
+			"bookworm": {"bullseye", "buster"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		debs     []*Package
+		expected []string
+	}{
+		{
+			"builds of the preferred fallback are used, even if older",
+			[]*Package{
+				testPackage("hello", "2.0", "buster"),
+				testPackage("hello", "1.5", "bullseye"),
+			},
+			[]string{"hello=1.5/bullseye"},
+		},
+		{
+			"builds of fallbacks are used if newer",
+			[]*Package{
+				testPackage("hello", "2.0", "bullseye"),
+				testPackage("hello", "1.0", "bookworm"),
+				testPackage("hello", "0.9", "bookworm"),
+			},
+			[]string{"hello=0.9/bookworm", "hello=1.0/bookworm", "hello=2.0/bullseye"},
+		},
+		{
+			"builds of fallbacks are not used if not newer",
+			[]*Package{
+				testPackage("hello", "1.0", "bullseye"),
+				testPackage("hello", "0.9", "buster"),
+				testPackage("hello", "1.0", "bookworm"),
+			},
+			[]string{"hello=1.0/bookworm"},
+		},
+		{
+			"newer builds of the preferred fallback are used",
+			[]*Package{
+				testPackage("hello", "3.0", "buster"),
+				testPackage("hello", "0.9", "bullseye"),
+				testPackage("hello", "2.0", "bullseye"),
+				testPackage("hello", "1.0", "bookworm"),
+			},
+			[]string{"hello=1.0/bookworm", "hello=2.0/bullseye"},
+		},
+		{
+			"the last fallback is used if there are no other builds",
+			[]*Package{
+				testPackage("hello", "2.0", "buster"),
+				testPackage("world", "1.0", "bullseye"),
+			},
+			[]string{"hello=2.0/buster", "world=1.0/bullseye"},
+		},
+		{
+			"builds for all suites are builds of suite",
+			[]*Package{
+				testPackage("hello", "0.9", "bullseye"),
+				testPackage("hello", "1.0", allSuites),
+			},
+			[]string{"hello=1.0/all"},
+		},
+		{
+			"builds of other suites are not used",
+			[]*Package{
+				testPackage("hello", "2.0", "jessie"),
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		published := testRepository(options, "bookworm", c.debs...)
+		if !reflect.DeepEqual(published, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, published)
+		}
+	}
+}
+
+func TestPruneRetention(t *testing.T) {
+	published := func(age time.Duration, p *Package) *Package {
+		p.PublishedAt = time.Now().Add(-age)
+		return p
+	}
+
+	prerelease := func(p *Package) *Package {
+		p.Component = "unstable"
+		return p
+	}
+
+	cases := []struct {
+		name      string
+		retention RetentionOptions
+		debs      []*Package
+		expected  []string
+	}{
+		{
+			"all versions are kept without retention",
+			RetentionOptions{},
+			[]*Package{
+				testPackage("hello", "1.0", allSuites),
+				testPackage("hello", "2.0", allSuites),
+			},
+			[]string{"hello=1.0/all", "hello=2.0/all"},
+		},
+		{
+			"the newest versions are kept",
+			RetentionOptions{KeepVersions: 2},
+			[]*Package{
+				testPackage("hello", "1.0", allSuites),
+				testPackage("hello", "3.0", allSuites),
+				testPackage("hello", "2.0", allSuites),
+				testPackage("world", "1.0", allSuites),
+			},
+			[]string{"hello=2.0/all", "hello=3.0/all", "world=1.0/all"},
+		},
+		{
+			"versions published recently are kept",
+			RetentionOptions{KeepNewerThan: Duration(24 * time.Hour)},
+			[]*Package{
+				published(72*time.Hour, testPackage("hello", "1.0", allSuites)),
+				published(time.Hour, testPackage("hello", "2.0", allSuites)),
+				published(time.Hour, testPackage("hello", "3.0", allSuites)),
+			},
+			[]string{"hello=2.0/all", "hello=3.0/all"},
+		},
+		{
+			"the newest version is kept even if old",
+			RetentionOptions{KeepNewerThan: Duration(24 * time.Hour)},
+			[]*Package{
+				published(96*time.Hour, testPackage("hello", "1.0", allSuites)),
+				published(72*time.Hour, testPackage("hello", "2.0", allSuites)),
+			},
+			[]string{"hello=2.0/all"},
+		},
+		{
+			"either of the limits keeps a version",
+			RetentionOptions{KeepVersions: 1, KeepNewerThan: Duration(24 * time.Hour)},
+			[]*Package{
+				published(72*time.Hour, testPackage("hello", "1.0", allSuites)),
+				published(time.Hour, testPackage("hello", "2.0", allSuites)),
+				published(time.Hour, testPackage("hello", "3.0", allSuites)),
+			},
+			[]string{"hello=2.0/all", "hello=3.0/all"},
+		},
+		{
+			"pre-releases do not push out releases",
+			RetentionOptions{KeepVersions: 1},
+			[]*Package{
+				testPackage("hello", "1.0", allSuites),
+				testPackage("hello", "2.0", allSuites),
+				prerelease(testPackage("hello", "3.0~rc1", allSuites)),
+				prerelease(testPackage("hello", "3.0~rc2", allSuites)),
+			},
+			[]string{"hello=2.0/all", "hello=3.0~rc2/all"},
+		},
+	}
+
+	for _, c := range cases {
+		options := &Options{Retention: c.retention}
+		published := testRepository(options, "bookworm", c.debs...)
+		if !reflect.DeepEqual(published, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, published)
+		}
+	}
+}
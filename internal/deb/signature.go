@@ -0,0 +1,99 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ayufan/debian-repository/internal/multi_hash"
+	"github.com/ayufan/debian-repository/internal/repository_cache"
+)
+
+var signatureLock sync.Mutex
+
+// signatureTag identifies signatures of repository, only signatures of the latest Release
+// are kept, so they are replaced when Release changes
+func (p *Repository) signatureTag() string {
+	key := strings.Join([]string{p.owner, p.repo, p.suiteName(), p.component, p.signingKey.Fingerprint()}, "/")
+	sum := sha256.Sum256([]byte(key))
+	return "signature-" + hex.EncodeToString(sum[:])
+}
+
+// sign returns Release.gpg or InRelease of release, signatures of known suites are cached
+// by content, so the same Release is always served with the same signature
+func (p *Repository) sign(fileName string, release *multi_hash.MultiHash) ([]byte, error) {
+	// suites of requests that are not published are not kept on disk
+	if !p.isKnown() {
+		return p.signRelease(fileName, release)
+	}
+
+	signatureLock.Lock()
+	defer signatureLock.Unlock()
+
+	tag := p.signatureTag()
+	releaseSHA256 := release.Hex("SHA256")
+
+	if data, err := repository_cache.Read(tag, "sha256"); err == nil && string(data) == releaseSHA256 {
+		if signature, err := repository_cache.Read(tag, fileName); err == nil {
+			return signature, nil
+		}
+	} else {
+		// remove signatures of the previous Release
+		repository_cache.Remove(tag, "Release.gpg")
+		repository_cache.Remove(tag, "InRelease")
+
+		repository_cache.Write(tag, "sha256", []byte(releaseSHA256))
+	}
+
+	signature, err := p.signRelease(fileName, release)
+	if err != nil {
+		return nil, err
+	}
+
+	repository_cache.Write(tag, fileName, signature)
+	return signature, nil
+}
+
+// signRelease makes Release.gpg or InRelease of release
+func (p *Repository) signRelease(fileName string, release *multi_hash.MultiHash) ([]byte, error) {
+	// signature is made at Date of Release
+	signedAt := p.newestUpdatedAt()
+
+	var signature bytes.Buffer
+	var err error
+
+	switch fileName {
+	case "Release.gpg":
+		err = p.signingKey.EncodeWithArmor(&signature, signedAt, bytesWriter(release.Bytes()))
+	case "InRelease":
+		err = p.signingKey.Encode(&signature, signedAt, bytesWriter(release.Bytes()))
+	default:
+		err = fmt.Errorf("unknown signature: %s", fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signature.Bytes(), nil
+}
+
+// signedWriter writes a signature of the current Release
+func (p *Repository) signedWriter(fileName string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		release, err := multi_hash.HashMe(p.WriteRelease)
+		if err != nil {
+			return err
+		}
+
+		signature, err := p.sign(fileName, release)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(signature)
+		return err
+	}
+}
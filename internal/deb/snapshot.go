@@ -0,0 +1,140 @@
+package deb
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/ayufan/debian-repository/internal/helpers"
+	"github.com/ayufan/debian-repository/internal/multi_hash"
+)
+
+// Hash returns a hash of file, a frozen file is not rendered again
+func (f *RepositoryFile) Hash() (*multi_hash.MultiHash, error) {
+	if f.hash != nil {
+		return f.hash, nil
+	}
+	return multi_hash.HashMe(f.Writer)
+}
+
+// frozenFiles returns a copy of rendered files, so callers can add to it
+func (p *Repository) frozenFiles(withRelease bool) map[string]*RepositoryFile {
+	files := make(map[string]*RepositoryFile, len(p.frozen))
+	for fileName, fileOpt := range p.frozen {
+		if !withRelease && isReleaseFile(fileName) {
+			continue
+		}
+		files[fileName] = fileOpt
+	}
+	return files
+}
+
+func isReleaseFile(fileName string) bool {
+	return fileName == "Release" || fileName == "Release.gpg" || fileName == "InRelease"
+}
+
+// Freeze renders all indices, Release and its signatures into memory,
+// the repository must not be modified afterwards, and can be served concurrently
+func (p *Repository) Freeze() error {
+	if p.frozen != nil {
+		return nil
+	}
+
+	files := p.Files()
+
+	// render indices first, then files listing them, then compressed variants
+	order := func(fileName string) int {
+		if helpers.IsCompressed(fileName) {
+			return 2
+		} else if files[fileName].uncompressed {
+			return 1
+		}
+		return 0
+	}
+
+	var fileNames []string
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	sort.SliceStable(fileNames, func(i, j int) bool {
+		return order(fileNames[i]) < order(fileNames[j])
+	})
+
+	for _, fileName := range fileNames {
+		fileOpt := files[fileName]
+
+		hash, err := fileOpt.Hash()
+		if err != nil {
+			return fmt.Errorf("%s: %v", fileName, err)
+		}
+		fileOpt.Writer = bytesWriter(hash.Bytes())
+		fileOpt.hash = hash
+
+		// a new generation is recorded before Packages.diff/Index is rendered,
+		// if it fails, Packages.diff/Index lists no patches until the next one
+		if files[path.Join(fileName+pdiffDir, pdiffIndex)] != nil {
+			err = p.recordPdiff(fileName, hash)
+			if err != nil {
+				log.Println("Skipping pdiff generation of", fileName, err)
+			}
+		}
+	}
+
+	p.validUntil = p.getValidUntil()
+	p.frozen = files
+
+	release, err := multi_hash.HashMe(p.WriteRelease)
+	if err != nil {
+		return err
+	}
+
+	releaseGpg, err := p.sign("Release.gpg", release)
+	if err != nil {
+		return err
+	}
+	inRelease, err := p.sign("InRelease", release)
+	if err != nil {
+		return err
+	}
+
+	p.frozen = p.frozenFiles(false)
+	p.frozen["Release"] = &RepositoryFile{Writer: bytesWriter(release.Bytes()), hash: release}
+	p.frozen["Release.gpg"] = &RepositoryFile{Writer: bytesWriter(releaseGpg)}
+	p.frozen["InRelease"] = &RepositoryFile{Writer: bytesWriter(inRelease)}
+
+	p.frozenSize = int64(len(releaseGpg) + len(inRelease))
+	for _, fileOpt := range p.frozen {
+		if fileOpt.hash != nil {
+			p.frozenSize += int64(len(fileOpt.hash.Bytes()))
+		}
+	}
+	return nil
+}
+
+// Size returns a number of bytes held by a frozen repository
+func (p *Repository) Size() int64 {
+	return p.frozenSize
+}
+
+// ModifiedAt returns when files last changed: the newest upload of packages, a Date of Release,
+// or when Release was signed with Valid-Until moved forward, it is the same for the same content
+func (p *Repository) ModifiedAt() time.Time {
+	modifiedAt := p.newestUpdatedAt()
+	if !p.validUntil.IsZero() {
+		signedAt := p.validUntil.Add(-time.Duration(p.options.Release.ValidFor))
+		if signedAt.After(modifiedAt) {
+			modifiedAt = signedAt
+		}
+	}
+	return modifiedAt
+}
+
+// Outdated returns true if a frozen Release should be signed again
+// with Valid-Until moved forward
+func (p *Repository) Outdated() bool {
+	return p.frozen == nil || !p.validUntil.Equal(p.getValidUntil()) ||
+		(!p.validUntil.IsZero() && time.Now().After(p.validUntil))
+}
@@ -0,0 +1,106 @@
+package deb
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/ayufan/debian-repository/internal/deb_key"
+)
+
+func testSigningKey(t *testing.T) *deb_key.Key {
+	config := &packet.Config{RSABits: 1024}
+	entity, err := openpgp.NewEntity("Test", "", "test@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	wr, err := armor.Encode(&buffer, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(wr, config); err != nil {
+		t.Fatal(err)
+	}
+	wr.Close()
+
+	key, err := deb_key.New(buffer.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestFreezeWithoutPdiffCache(t *testing.T) {
+	// the default cache directory does not exist, so generations cannot be recorded
+	if _, err := os.Stat("tmp-cache"); os.Getenv("REPOSITORY_CACHE") != "" || err == nil {
+		t.Skip("repository cache is writable")
+	}
+
+	PdiffHistory = 2
+	defer func() { PdiffHistory = 0 }()
+
+	repository := NewRepository("owner", "repo", "bionic", "", "", testSigningKey(t), nil)
+	deb := testPackage("hello", "1.0", "bionic")
+	deb.Archive = &Archive{}
+	repository.Add(deb)
+
+	if err := repository.Freeze(); err != nil {
+		t.Fatal("snapshot is not frozen:", err)
+	}
+
+	for _, fileName := range []string{"InRelease", "releases/binary-amd64/Packages", "releases/binary-amd64/Packages.diff/Index"} {
+		if file, err := repository.File(fileName); file == nil || err != nil {
+			t.Errorf("%s is not served: %v", fileName, err)
+		}
+	}
+}
+
+func TestModifiedAt(t *testing.T) {
+	options := DefaultOptions()
+	repository := NewRepository("owner", "repo", "bionic", "", "", nil, options)
+	deb := testPackage("hello", "1.0", "bionic")
+	deb.UpdatedAt = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	repository.Add(deb)
+
+	if modifiedAt := repository.ModifiedAt(); !modifiedAt.Equal(deb.UpdatedAt) {
+		t.Errorf("ModifiedAt() = %v, expected the newest upload", modifiedAt)
+	}
+
+	// Release is signed again with Valid-Until moved forward
+	options.Release.ValidFor = Duration(24 * time.Hour)
+	repository.validUntil = repository.getValidUntil()
+	if modifiedAt := repository.ModifiedAt(); !modifiedAt.Equal(repository.validUntil.Add(-24 * time.Hour)) {
+		t.Errorf("ModifiedAt() = %v, expected when Release was signed", modifiedAt)
+	}
+}
+
+func TestByHashFileOfSnapshot(t *testing.T) {
+	// without a cache of previous generations, the current one is served from the snapshot
+	repository := NewRepository("owner", "repo", "bionic", "", "", testSigningKey(t), nil)
+	deb := testPackage("hello", "1.0", "bionic")
+	deb.Archive = &Archive{}
+	repository.Add(deb)
+	if err := repository.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	packages, _ := repository.File("releases/binary-amd64/Packages")
+	hash, _ := packages.Hash()
+	for _, hashName := range []string{"MD5Sum", "SHA256"} {
+		fileName := byHashPath("releases/binary-amd64/Packages", hashName, hash)
+		if file, err := repository.File(fileName); file != packages || err != nil {
+			t.Errorf("%s is not served from snapshot: %v", fileName, err)
+		}
+	}
+
+	if file, _ := repository.File("releases/binary-amd64/by-hash/SHA256/0000"); file != nil {
+		t.Error("unknown digest is served")
+	}
+}
@@ -0,0 +1,170 @@
+package deb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stapelberg/godebiancontrol"
+	"golang.org/x/crypto/openpgp/clearsign"
+
+	"github.com/ayufan/debian-repository/internal/multi_hash"
+	"github.com/ayufan/debian-repository/internal/repository_cache"
+)
+
+// sourceChecksums maps fields of .dsc to the hashes
+var sourceChecksums = map[string]string{
+	"Files":            "MD5Sum",
+	"Checksums-Sha1":   "SHA1",
+	"Checksums-Sha256": "SHA256",
+	"Checksums-Sha512": "SHA512",
+}
+
+// stripSignature returns a content of clearsigned .dsc,
+// or the unchanged data if it is not signed
+func stripSignature(data []byte) []byte {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return data
+	}
+
+	plaintext := block.Plaintext
+	if !bytes.HasSuffix(plaintext, []byte("\n")) {
+		plaintext = append(plaintext, '\n')
+	}
+	return plaintext
+}
+
+// sourceStanza converts .dsc into a stanza of Sources:
+// the Source is renamed to Package and the .dsc itself is added to the list of files
+func sourceStanza(dsc []byte, fileName string, m *multi_hash.MultiHash) []byte {
+	var buffer bytes.Buffer
+	var field string
+
+	flushField := func() {
+		if hashName, ok := sourceChecksums[field]; ok {
+			m.WriteReleaseHash(&buffer, hashName, fileName)
+		}
+	}
+
+	scanner := newLineScanner(dsc)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			flushField()
+			field = strings.SplitN(line, ":", 2)[0]
+
+			if field == "Source" {
+				line = "Package" + strings.TrimPrefix(line, "Source")
+			}
+		}
+
+		buffer.WriteString(line)
+		buffer.WriteByte('\n')
+	}
+	flushField()
+
+	return buffer.Bytes()
+}
+
+// SourceFiles returns a list of files referenced by a source package
+func SourceFiles(paragraph godebiancontrol.Paragraph) (files []string) {
+	for _, line := range strings.Split(paragraph["Files"], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 {
+			files = append(files, fields[2])
+		}
+	}
+	return
+}
+
+func (d *Archive) parseSource(r io.Reader, fileName string) error {
+	m := multi_hash.New()
+
+	data, err := ioutil.ReadAll(io.TeeReader(r, m))
+	if err != nil {
+		return err
+	}
+
+	dsc := stripSignature(data)
+
+	paragraphs, err := godebiancontrol.Parse(bytes.NewReader(dsc))
+	if err != nil {
+		return err
+	}
+	if len(paragraphs) != 1 {
+		return errors.New("expected exactly one paragraph in .dsc")
+	}
+	if paragraphs[0]["Source"] == "" {
+		return errors.New("missing Source from .dsc")
+	}
+	if len(SourceFiles(paragraphs[0])) == 0 {
+		return errors.New("missing Files from .dsc")
+	}
+
+	d.Control = sourceStanza(dsc, fileName, m)
+	return nil
+}
+
+func (d *Archive) readSourceFromCache(tag string) error {
+	data, err := repository_cache.Read(tag, "source")
+	if err != nil {
+		return err
+	}
+
+	d.Control = data
+	return nil
+}
+
+func (d *Archive) writeSourceToCache(tag string) error {
+	return repository_cache.Write(tag, "source", d.Control)
+}
+
+func ReadSource(r io.Reader, fileName string) (*Archive, error) {
+	dsc := &Archive{}
+	err := dsc.parseSource(r, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dsc, nil
+}
+
+func ReadSourceFromURL(url, fileName, cacheKey string) (dsc *Archive, err error) {
+	dsc = &Archive{}
+	if dsc.readSourceFromCache(cacheKey) == nil {
+		return dsc, nil
+	}
+
+	started := time.Now()
+	defer func() {
+		log.Println("Readed", url, "in", time.Since(started), err)
+	}()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %q", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("http status code: %d %s", resp.StatusCode, resp.Status)
+	}
+	defer resp.Body.Close()
+
+	dsc, err = ReadSource(resp.Body, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	dsc.writeSourceToCache(cacheKey)
+	return dsc, nil
+}
@@ -0,0 +1,89 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const testDsc = `Format: 3.0 (quilt)
+Source: hello
+Binary: hello, hello-dbgsym
+Architecture: any
+Version: 1.0-1
+Maintainer: Jane Doe <jane@example.org>
+Build-Depends: debhelper-compat (= 13)
+Checksums-Sha256:
+ aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa 1024 hello_1.0.orig.tar.gz
+ bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb 512 hello_1.0-1.debian.tar.xz
+Files:
+ cccccccccccccccccccccccccccccccc 1024 hello_1.0.orig.tar.gz
+ dddddddddddddddddddddddddddddddd 512 hello_1.0-1.debian.tar.xz
+`
+
+func testClearsign(t *testing.T, data string) []byte {
+	config := &packet.Config{RSABits: 1024}
+	entity, err := openpgp.NewEntity("Test", "", "test@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signed bytes.Buffer
+	wr, err := clearsign.Encode(&signed, entity.PrivateKey, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wr.Write([]byte(data))
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return signed.Bytes()
+}
+
+func TestReadSource(t *testing.T) {
+	signed := testClearsign(t, testDsc)
+
+	dsc, err := ReadSource(bytes.NewReader(signed), "hello_1.0-1.dsc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hashes of .dsc are of the signed file, as it is downloaded by apt
+	md5sum := md5.Sum(signed)
+	sha256sum := sha256.Sum256(signed)
+
+	expected := `Format: 3.0 (quilt)
+Package: hello
+Binary: hello, hello-dbgsym
+Architecture: any
+Version: 1.0-1
+Maintainer: Jane Doe <jane@example.org>
+Build-Depends: debhelper-compat (= 13)
+Checksums-Sha256:
+ aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa 1024 hello_1.0.orig.tar.gz
+ bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb 512 hello_1.0-1.debian.tar.xz
+` + fmt.Sprintf(" %s %d hello_1.0-1.dsc\n", hex.EncodeToString(sha256sum[:]), len(signed)) + `Files:
+ cccccccccccccccccccccccccccccccc 1024 hello_1.0.orig.tar.gz
+ dddddddddddddddddddddddddddddddd 512 hello_1.0-1.debian.tar.xz
+` + fmt.Sprintf(" %s %d hello_1.0-1.dsc\n", hex.EncodeToString(md5sum[:]), len(signed))
+
+	if string(dsc.Control) != expected {
+		t.Errorf("stanza:\n%s\nexpected:\n%s", dsc.Control, expected)
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	if stripped := stripSignature([]byte(testDsc)); string(stripped) != testDsc {
+		t.Errorf("unsigned .dsc is changed: %q", stripped)
+	}
+	if stripped := stripSignature(testClearsign(t, testDsc)); string(stripped) != testDsc {
+		t.Errorf("signature is not stripped: %q", stripped)
+	}
+}
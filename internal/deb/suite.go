@@ -0,0 +1,234 @@
+package deb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+// allSuites is assigned to packages that are published in every suite
+const allSuites = "all"
+
+// KnownSuites are codenames of Debian and Ubuntu that are discovered anywhere
+// in version or file name, others are discovered only from their suffixes
+var KnownSuites = []string{
+	"wheezy", "jessie", "stretch", "buster", "bullseye", "bookworm", "trixie", "forky", "duke", "sid",
+	"precise", "trusty", "xenial", "bionic", "cosmic", "disco", "eoan", "focal", "groovy",
+	"hirsute", "impish", "jammy", "kinetic", "lunar", "mantic", "noble", "oracular", "plucky",
+	"questing", "resolute",
+}
+
+// notSuites are words of version suffixes that are not codenames, ex. 1.0~rc1
+var notSuites = []string{
+	"alpha", "beta", "rc", "pre", "dev", "git", "svn", "bzr", "hg", "snapshot", "nightly",
+	"build", "debian", "ubuntu", "deb", "dfsg", "ds", "repack", "really", "exp", "bpo",
+}
+
+// SuiteRule assigns packages to suites, all conditions that are set have to match
+type SuiteRule struct {
+	// Suites are assigned when rule matches,
+	// if empty, the first group of patterns or a value of control field is used
+	Suites []string `json:"suites"`
+
+	// ControlField is a field of control with a list of suites, ex. "X-Suite"
+	ControlField string `json:"control_field"`
+
+	Version    *Regexp `json:"version"`
+	FileName   *Regexp `json:"file_name"`
+	AssetLabel *Regexp `json:"asset_label"`
+
+	// ReleaseBody matches a metadata in description of release, ex. "(?m)^Suite: (\\w+)$"
+	ReleaseBody *Regexp `json:"release_body"`
+
+	// excluded are not suites, a rule matching only them does not match
+	excluded []string
+}
+
+// SuiteRules are checked in order, the first matching one is used
+type SuiteRules []SuiteRule
+
+func (r *SuiteRules) UnmarshalJSON(data []byte) error {
+	rules, err := unmarshalRules[SuiteRule](data)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		patterns := []*Regexp{rule.Version, rule.FileName, rule.AssetLabel, rule.ReleaseBody}
+
+		var conditions, groups int
+		for _, pattern := range patterns {
+			if pattern != nil {
+				conditions++
+				groups += pattern.NumSubexp()
+			}
+		}
+
+		if rule.ControlField == "" && conditions == 0 {
+			return errors.New("suite rule without conditions")
+		}
+
+		// suites are taken from the first group of patterns, if not set
+		if len(rule.Suites) == 0 && rule.ControlField == "" && groups == 0 {
+			return errors.New("suite rule without suites, control field or a group in pattern")
+		}
+	}
+
+	*r = rules
+	return nil
+}
+
+// splitSuites splits a list of suites separated by spaces or commas
+func splitSuites(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+func (r *SuiteRule) detect(p *Package, release *github.RepositoryRelease, asset *github.ReleaseAsset) (suites []string, reasons []string) {
+	if r.ControlField != "" {
+		value := p.paragraphs[r.ControlField]
+		if value == "" {
+			return nil, nil
+		}
+		suites = splitSuites(value)
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r.ControlField, value))
+	}
+
+	patterns := []struct {
+		name    string
+		pattern *Regexp
+		value   string
+	}{
+		{"version", r.Version, p.Version()},
+		{"file name", r.FileName, asset.GetName()},
+		{"asset label", r.AssetLabel, asset.GetLabel()},
+		{"release body", r.ReleaseBody, release.GetBody()},
+	}
+
+	for _, pattern := range patterns {
+		if pattern.pattern == nil {
+			continue
+		}
+
+		matches := pattern.pattern.FindStringSubmatch(pattern.value)
+		if matches == nil {
+			return nil, nil
+		}
+		if len(matches) > 1 && matches[1] != "" {
+			suites = append(suites, splitSuites(matches[1])...)
+		}
+		reasons = append(reasons, fmt.Sprintf("%s matches %q", pattern.name, matches[0]))
+	}
+
+	if len(r.excluded) > 0 {
+		var filtered []string
+		for _, suite := range suites {
+			if !contains(r.excluded, suite) {
+				filtered = append(filtered, suite)
+			}
+		}
+		suites = filtered
+	}
+
+	if len(r.Suites) > 0 {
+		suites = r.Suites
+	}
+	return suites, reasons
+}
+
+// suiteNames returns all names of suites that can be discovered
+func (o *Options) suiteNames() []string {
+	var names []string
+	for _, lists := range [][]string{Suites, KnownSuites, o.SuiteDiscovery.Allowed} {
+		for _, name := range lists {
+			if name != "" && !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range o.SuiteDiscovery.Aliases {
+		if name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var suffixVersionRegexp = regexp.MustCompile(`~([a-z]+)[0-9]*$`)
+var suffixFileNameRegexp = regexp.MustCompile(`^[^_]+_[^_]+_[^_]+_([a-z]+)[0-9]*\.[a-z]+$`)
+
+var defaultSuiteRulesLock sync.Mutex
+
+// defaultSuiteRulesCache holds rules built for a list of suite names,
+// there are only a few of them, as names depend only on config
+var defaultSuiteRulesCache = make(map[string]SuiteRules)
+
+// defaultSuiteRules uses X-Suite of control, or a name of known suite
+// in version, ex. 1.0~bookworm1, or in file name, ex. hello_1.0_arm64_bookworm.deb,
+// or any other codename in a suffix of them, ex. 1.0~questing1 or hello_1.0_arm64_questing.deb
+func defaultSuiteRules(options *Options) SuiteRules {
+	var quoted []string
+	for _, suite := range options.suiteNames() {
+		quoted = append(quoted, regexp.QuoteMeta(suite))
+	}
+	names := strings.Join(quoted, "|")
+
+	defaultSuiteRulesLock.Lock()
+	defer defaultSuiteRulesLock.Unlock()
+
+	if rules, ok := defaultSuiteRulesCache[names]; ok {
+		return rules
+	}
+
+	rules := SuiteRules{
+		{ControlField: "X-Suite"},
+		{Version: &Regexp{regexp.MustCompile(`(?:^|[^a-z])(` + names + `)(?:[^a-z]|$)`)}},
+		{FileName: &Regexp{regexp.MustCompile(`[_~+](` + names + `)[0-9]*(?:_|\.[a-z]+$)`)}},
+		{Version: &Regexp{suffixVersionRegexp}, excluded: notSuites},
+		{FileName: &Regexp{suffixFileNameRegexp}, excluded: notSuites},
+	}
+	defaultSuiteRulesCache[names] = rules
+	return rules
+}
+
+// detectSuites returns suites of package with a reason,
+// packages not matching any rule are published in all suites
+func (p *Package) detectSuites(release *github.RepositoryRelease, asset *github.ReleaseAsset, options *Options) ([]string, string) {
+	rules := options.SuiteRules
+	if len(rules) == 0 {
+		rules = defaultSuiteRules(options)
+	}
+
+	for _, rule := range rules {
+		suites, reasons := rule.detect(p, release, asset)
+		if len(suites) > 0 {
+			return suites, strings.Join(reasons, ", ")
+		}
+	}
+
+	return []string{allSuites}, "no suite rule matched"
+}
+
+// fallbackSuites returns suites used by suite in order of preference,
+// fallbacks of fallback suites are included
+func (o *Options) fallbackSuites(suite string) []string {
+	var suites []string
+	queue := o.SuiteFallbacks[suite]
+
+	for len(queue) > 0 {
+		fallback := queue[0]
+		queue = queue[1:]
+
+		if fallback == suite || contains(suites, fallback) {
+			continue
+		}
+		suites = append(suites, fallback)
+		queue = append(queue, o.SuiteFallbacks[fallback]...)
+	}
+	return suites
+}
@@ -0,0 +1,160 @@
+package deb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func testDetectSuites(options *Options, fields map[string]string, fileName, label, body string) []string {
+	p := &Package{paragraphs: map[string]string{"Package": "hello", "Version": "1.0"}}
+	for field, value := range fields {
+		p.paragraphs[field] = value
+	}
+
+	release := &github.RepositoryRelease{Body: github.String(body)}
+	asset := &github.ReleaseAsset{Name: github.String(fileName), Label: github.String(label)}
+
+	suites, _ := p.detectSuites(release, asset, options)
+	return suites
+}
+
+func TestDefaultSuiteRules(t *testing.T) {
+	cases := []struct {
+		name     string
+		fields   map[string]string
+		fileName string
+		expected []string
+	}{
+		{
+			"name of package is not a suite",
+			map[string]string{"Package": "xenial-tools"},
+			"xenial-tools_1.0_amd64.deb",
+			[]string{allSuites},
+		},
+		{
+			"suite in version",
+			map[string]string{"Version": "1.0~bionic1"},
+			"hello_1.0~bionic1_amd64.deb",
+			[]string{"bionic"},
+		},
+		{
+			"suite in a word of version",
+			map[string]string{"Version": "1.0+xenialfix1"},
+			"hello_1.0_amd64.deb",
+			[]string{allSuites},
+		},
+		{
+			"suite in file name",
+			nil,
+			"hello_1.0_arm64_bookworm.deb",
+			[]string{"bookworm"},
+		},
+		{
+			"new codename in version",
+			map[string]string{"Version": "1.0+questing"},
+			"hello_1.0+questing_amd64.deb",
+			[]string{"questing"},
+		},
+		{
+			"unknown codename in suffix of version",
+			map[string]string{"Version": "1.0-1~zesty2"},
+			"hello_1.0-1~zesty2_amd64.deb",
+			[]string{"zesty"},
+		},
+		{
+			"unknown codename in suffix of file name",
+			nil,
+			"hello_1.0_arm64_zesty.deb",
+			[]string{"zesty"},
+		},
+		{
+			"pre-release in suffix of version",
+			map[string]string{"Version": "1.0~rc1"},
+			"hello_1.0~rc1_amd64.deb",
+			[]string{allSuites},
+		},
+		{
+			"X-Suite lists several suites",
+			map[string]string{"X-Suite": "bookworm, bullseye"},
+			"hello_1.0_amd64_focal.deb",
+			[]string{"bookworm", "bullseye"},
+		},
+	}
+
+	for _, c := range cases {
+		suites := testDetectSuites(DefaultOptions(), c.fields, c.fileName, "", "")
+		if !reflect.DeepEqual(suites, c.expected) {
+			t.Errorf("%s: suites = %q, expected %q", c.name, suites, c.expected)
+		}
+	}
+}
+
+func TestDefaultSuiteRulesAreReused(t *testing.T) {
+	rules := defaultSuiteRules(DefaultOptions())
+	if again := defaultSuiteRules(DefaultOptions()); rules[1].Version != again[1].Version {
+		t.Error("rules are built again for the same suites")
+	}
+
+	options := &Options{SuiteDiscovery: SuiteDiscoveryOptions{Allowed: []string{"custom"}}}
+	if other := defaultSuiteRules(options); rules[1].Version == other[1].Version {
+		t.Error("rules are reused for other suites")
+	}
+	if suites := testDetectSuites(options, nil, "hello_1.0_amd64_custom.deb", "", ""); !reflect.DeepEqual(suites, []string{"custom"}) {
+		t.Errorf("suites = %q, expected custom", suites)
+	}
+}
+
+func TestSuiteRules(t *testing.T) {
+	options := DefaultOptions()
+	err := strictUnmarshal([]byte(`{"suite_rules": [
+		{"asset_label": "^LTS", "suites": ["focal", "jammy"]},
+		{"release_body": "(?m)^Suites: ([a-z, ]+)$"},
+		{"control_field": "X-Distribution"}
+	]}`), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		fields   map[string]string
+		label    string
+		body     string
+		expected []string
+	}{
+		{"suites of rule", nil, "LTS build", "Suites: noble", []string{"focal", "jammy"}},
+		{"suites of release body", nil, "", "Notes\nSuites: noble, oracular\n", []string{"noble", "oracular"}},
+		{"suites of control field", map[string]string{"X-Distribution": "sid"}, "", "", []string{"sid"}},
+		{"default rules are not used", map[string]string{"Version": "1.0~bionic1"}, "", "", []string{allSuites}},
+	}
+
+	for _, c := range cases {
+		suites := testDetectSuites(options, c.fields, "hello_1.0_amd64.deb", c.label, c.body)
+		if !reflect.DeepEqual(suites, c.expected) {
+			t.Errorf("%s: suites = %q, expected %q", c.name, suites, c.expected)
+		}
+	}
+}
+
+func TestDiscoveredSuitesAreAllowed(t *testing.T) {
+	options := &Options{SuiteDiscovery: SuiteDiscoveryOptions{Allowed: []string{"bookworm"}}}
+
+	p := testPackage("hello", "1.0~zesty1", "zesty")
+	p.Suites = testDetectSuites(options, map[string]string{"Version": "1.0~zesty1"}, "hello_1.0~zesty1_amd64.deb", "", "")
+	if !reflect.DeepEqual(p.Suites, []string{"zesty"}) {
+		t.Fatalf("suites = %q, expected zesty", p.Suites)
+	}
+
+	// a package of not allowed suite is not published in other suites
+	if published := testRepository(options, "bookworm", p); published != nil {
+		t.Errorf("package of not allowed suite is published: %v", published)
+	}
+
+	repository := NewRepository("owner", "repo", "zesty", "", "", nil, options)
+	repository.Add(p)
+	if suites := repository.Suites(); contains(suites, "zesty") {
+		t.Errorf("not allowed suite is discovered: %q", suites)
+	}
+}
@@ -0,0 +1,66 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// splitDescription returns a control with only the short description and Description-md5,
+// and the full description as used by Translation- files
+func splitDescription(control []byte) ([]byte, string) {
+	var buffer bytes.Buffer
+	var description []string
+	var inDescription, hasMd5 bool
+
+	scanner := newLineScanner(control)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line != "" && (line[0] == ' ' || line[0] == '\t') {
+			if inDescription {
+				description = append(description, line)
+				continue
+			}
+		} else {
+			inDescription = strings.HasPrefix(line, "Description:")
+			hasMd5 = hasMd5 || strings.HasPrefix(line, "Description-md5:")
+			if inDescription {
+				description = append(description, strings.TrimSpace(strings.TrimPrefix(line, "Description:")))
+			}
+		}
+
+		buffer.WriteString(line)
+		buffer.WriteByte('\n')
+	}
+
+	if scanner.Err() != nil || len(description) == 0 || hasMd5 {
+		return control, ""
+	}
+
+	fullDescription := strings.Join(description, "\n") + "\n"
+	fmt.Fprintln(&buffer, "Description-md5:", DescriptionMd5(fullDescription))
+	return buffer.Bytes(), fullDescription
+}
+
+// DescriptionMd5 returns a hash of the full description
+func DescriptionMd5(description string) string {
+	md5sum := md5.Sum([]byte(description))
+	return hex.EncodeToString(md5sum[:])
+}
+
+func (p *Package) WriteTranslation(w io.Writer) bool {
+	_, description := splitDescription(p.Control)
+	if description == "" {
+		return false
+	}
+
+	fmt.Fprintln(w, "Package:", p.Name())
+	fmt.Fprintln(w, "Description-md5:", DescriptionMd5(description))
+	fmt.Fprint(w, "Description-en: ", description)
+	fmt.Fprintln(w)
+	return true
+}
@@ -0,0 +1,58 @@
+package deb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitDescription(t *testing.T) {
+	control := "Package: hello\n" +
+		"Description: greets the world\n" +
+		" Hello prints a greeting.\n" +
+		" .\n" +
+		" It is friendly.\n" +
+		"Section: utils\n"
+
+	stripped, description := splitDescription([]byte(control))
+
+	// as hashed by apt: the first line and continuation lines, with a trailing newline
+	expectedDescription := "greets the world\n Hello prints a greeting.\n .\n It is friendly.\n"
+	if description != expectedDescription {
+		t.Errorf("description = %q, expected %q", description, expectedDescription)
+	}
+
+	expectedControl := "Package: hello\n" +
+		"Description: greets the world\n" +
+		"Section: utils\n" +
+		"Description-md5: 33d7c2b52408ec65c9d70435602b3fd4\n"
+	if string(stripped) != expectedControl {
+		t.Errorf("control = %q, expected %q", stripped, expectedControl)
+	}
+}
+
+func TestSplitDescriptionWithMd5(t *testing.T) {
+	control := "Package: hello\n" +
+		"Description: greets the world\n" +
+		" Hello prints a greeting.\n" +
+		"Description-md5: 0123456789abcdef0123456789abcdef\n"
+
+	stripped, description := splitDescription([]byte(control))
+	if string(stripped) != control || description != "" {
+		t.Errorf("control with Description-md5 is changed: %q", stripped)
+	}
+}
+
+func TestSplitDescriptionLongLine(t *testing.T) {
+	longLine := " " + strings.Repeat("x", 100*1024)
+	control := "Package: hello\n" +
+		"Description: greets the world\n" +
+		longLine + "\n"
+
+	stripped, description := splitDescription([]byte(control))
+	if description != "greets the world\n"+longLine+"\n" {
+		t.Errorf("description of %d bytes, expected %d", len(description), len(longLine)+18)
+	}
+	if !strings.Contains(string(stripped), "Description-md5: "+DescriptionMd5(description)) {
+		t.Errorf("control without Description-md5: %q", stripped)
+	}
+}
@@ -0,0 +1,242 @@
+package deb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a Debian package version: [epoch:]upstream_version[-debian_revision]
+type Version struct {
+	Epoch    int
+	Upstream string
+	Revision string
+}
+
+// versionWarning is a problem that dpkg only warns about
+type versionWarning string
+
+func (w versionWarning) Error() string {
+	return string(w)
+}
+
+// ParseVersion parses a version the same way as dpkg does.
+// The parsed version is returned even if it is invalid,
+// so it can still be compared.
+func ParseVersion(version string) (v Version, err error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return v, errors.New("version string is empty")
+	}
+	if strings.ContainsAny(version, " \t\n") {
+		err = errors.New("version string has embedded spaces")
+	}
+
+	if idx := strings.Index(version, ":"); idx >= 0 {
+		epoch := version[0:idx]
+		version = version[idx+1:]
+
+		if e, convErr := strconv.ParseUint(epoch, 10, 31); convErr == nil {
+			v.Epoch = int(e)
+		} else if err == nil && epoch == "" {
+			err = errors.New("epoch in version is empty")
+		} else if err == nil {
+			err = errors.New("epoch in version is not number")
+		}
+		if err == nil && version == "" {
+			err = errors.New("nothing after colon in version number")
+		}
+	}
+
+	v.Upstream = version
+	if idx := strings.LastIndex(version, "-"); idx >= 0 {
+		v.Upstream = version[0:idx]
+		v.Revision = version[idx+1:]
+
+		if err == nil && v.Revision == "" {
+			err = errors.New("revision number is empty")
+		}
+	}
+
+	if err != nil {
+		return v, err
+	}
+
+	if v.Upstream == "" {
+		return v, errors.New("version number is empty")
+	}
+	if !isDigit(v.Upstream[0]) {
+		return v, versionWarning("version number does not start with digit")
+	}
+	for _, c := range []byte(v.Upstream) {
+		if !isDigit(c) && !isAlpha(c) && strings.IndexByte(".-+~:", c) < 0 {
+			return v, versionWarning(fmt.Sprintf("invalid character in version number: %q", c))
+		}
+	}
+	for _, c := range []byte(v.Revision) {
+		if !isDigit(c) && !isAlpha(c) && strings.IndexByte(".+~", c) < 0 {
+			return v, versionWarning(fmt.Sprintf("invalid character in revision number: %q", c))
+		}
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	version := v.Upstream
+	if v.Epoch != 0 {
+		version = strconv.Itoa(v.Epoch) + ":" + version
+	}
+	if v.Revision != "" {
+		version += "-" + v.Revision
+	}
+	return version
+}
+
+// Canonical returns a representation that is equal for all versions
+// that dpkg considers to be equal, ex. 0:1.01-0 and 1.1
+func (v Version) Canonical() string {
+	version := canonicalVersionPart(v.Upstream)
+	if v.Epoch != 0 {
+		version = strconv.Itoa(v.Epoch) + ":" + version
+	}
+	if revision := canonicalVersionPart(v.Revision); revision != "0" {
+		version += "-" + revision
+	}
+	return version
+}
+
+// Compare returns -1, 0 or 1 when v is older, equal or newer than other
+func (v Version) Compare(other Version) int {
+	if v.Epoch < other.Epoch {
+		return -1
+	} else if v.Epoch > other.Epoch {
+		return 1
+	}
+
+	if result := compareVersionPart(v.Upstream, other.Upstream); result != 0 {
+		return result
+	}
+
+	return compareVersionPart(v.Revision, other.Revision)
+}
+
+// CompareVersions compares two version strings, even if they are not valid
+func CompareVersions(a, b string) int {
+	va, _ := ParseVersion(a)
+	vb, _ := ParseVersion(b)
+	return va.Compare(vb)
+}
+
+// NormalizeVersion returns a canonical form of version string
+func NormalizeVersion(version string) string {
+	v, _ := ParseVersion(version)
+	return v.Canonical()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// versionCharOrder sorts: tilde first, then the end of part, then letters
+// and then all other characters
+func versionCharOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareVersionPart(a, b string) int {
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		// compare non-digit prefix
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac := versionCharOrder(a, i)
+			bc := versionCharOrder(b, j)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		// compare numeric part
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// canonicalVersionPart rewrites each number without leading zeros,
+// and makes the implicit zero after a trailing non-digit explicit
+func canonicalVersionPart(s string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(s) || result.Len() == 0; {
+		j := i
+		for j < len(s) && !isDigit(s[j]) {
+			j++
+		}
+		result.WriteString(s[i:j])
+
+		k := j
+		for k < len(s) && isDigit(s[k]) {
+			k++
+		}
+		digits := strings.TrimLeft(s[j:k], "0")
+		if digits == "" {
+			digits = "0"
+		}
+		result.WriteString(digits)
+		i = k
+	}
+	return result.String()
+}
+
+func sign(value int) int {
+	if value < 0 {
+		return -1
+	} else if value > 0 {
+		return 1
+	}
+	return 0
+}
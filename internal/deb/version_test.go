@@ -0,0 +1,128 @@
+package deb
+
+import (
+	"testing"
+)
+
+// Based on t/dpkg_version.t of dpkg
+var versionComparisons = []struct {
+	a, b     string
+	expected int
+}{
+	{"1.0", "1.0", 0},
+	{"1.0", "2.0", -1},
+	{"2.2~rc-4", "2.2-1", -1},
+	{"2.2-1", "2.2~rc-4", 1},
+	{"1.0000-1", "1.0-1", 0},
+	{"1", "0:1", 0},
+	{"0", "0:0-0", 0},
+	{"2:2.5", "1:7.5", 1},
+	{"1:0foo", "0foo", 1},
+	{"0:0foo", "0foo", 0},
+	{"0foo", "0foo", 0},
+	{"0foo-0", "0foo", 0},
+	{"0foo", "0foo-0", 0},
+	{"0foo", "0fo", 1},
+	{"0foo-0", "0foo+", -1},
+	{"0foo~1", "0foo", -1},
+	{"0foo~foo+Bar", "0foo~foo+bar", -1},
+	{"0foo~~", "0foo~", -1},
+	{"1~", "1", -1},
+	{"12345+that-really-is-some-ver-0", "12345+that-really-is-some-ver-10", -1},
+	{"0foo-0", "0foo-01", -1},
+	{"0foo.bar", "0foobar", 1},
+	{"0foo.bar", "0foo1bar", 1},
+	{"0foo.bar", "0foo0bar", 1},
+	{"0foo1bar-1", "0foobar-1", -1},
+	{"0foo2.0", "0foo2", 1},
+	{"0foo2.0.0", "0foo2.10.0", -1},
+	{"0foo2.0", "0foo2.0.0", -1},
+	{"0foo2.0", "0foo2.10", -1},
+	{"0foo2.1", "0foo2.10", -1},
+	{"1.09", "1.9", 0},
+	{"1.0.8+nmu1", "1.0.8", 1},
+	{"3.11", "3.10+nmu1", 1},
+	{"0.9j-20080306-4", "0.9i-20070324-2", 1},
+	{"1.2.0~b7-1", "1.2.0~b6-1", 1},
+	{"1.011-1", "1.06-2", 1},
+	{"0.0.9+dfsg1-1", "0.0.8+dfsg1-3", 1},
+	{"4.6.99+svn6582-1", "4.6.99+svn6496-1", 1},
+	{"53", "52", 1},
+	{"0.9.9~pre122-1", "0.9.9~pre111-1", 1},
+	{"2:2.3.2-2+lenny2", "2:2.3.2-2", 1},
+	{"1:3.8.1-1", "3.8.GA-1", 1},
+	{"1.0.1+gpl-1", "1.0.1-2", 1},
+	{"1a", "1000a", -1},
+	{"1.10", "1.9", 1},
+	{"1.0~rc1", "1.0", -1},
+	{"1.0", "1.", 0},
+	{"1~", "1~0", 0},
+	{"1.a", "1.a0", 0},
+	{"1.0a", "1.a", -1},
+	{"1.0-0", "1.0", 0},
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range versionComparisons {
+		if result := CompareVersions(test.a, test.b); result != test.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", test.a, test.b, result, test.expected)
+		}
+		if result := CompareVersions(test.b, test.a); result != -test.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", test.b, test.a, result, -test.expected)
+		}
+
+		equal := NormalizeVersion(test.a) == NormalizeVersion(test.b)
+		if equal != (test.expected == 0) {
+			t.Errorf("NormalizeVersion(%q) = %q, NormalizeVersion(%q) = %q, expected equal: %v",
+				test.a, NormalizeVersion(test.a), test.b, NormalizeVersion(test.b), test.expected == 0)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected Version
+	}{
+		{"0", Version{0, "0", ""}},
+		{"0:0", Version{0, "0", ""}},
+		{"0:0-0", Version{0, "0", "0"}},
+		{"0:0.0-0.0", Version{0, "0.0", "0.0"}},
+		{"0:0.0:0-0", Version{0, "0.0:0", "0"}},
+		{"0:0.0-0:0-0", Version{0, "0.0-0:0", "0"}},
+		{"1:2.3~rc1+dfsg-4ubuntu1", Version{1, "2.3~rc1+dfsg", "4ubuntu1"}},
+	}
+
+	for _, test := range tests {
+		v, err := ParseVersion(test.version)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) = %v", test.version, err)
+		}
+		if v != test.expected {
+			t.Errorf("ParseVersion(%q) = %#v, expected %#v", test.version, v, test.expected)
+		}
+	}
+}
+
+func TestParseVersionErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"0:",
+		":1.0",
+		"a:1.0",
+		"-1:1.0",
+		"1.0-",
+		"0:0-",
+		"1.0 1.0",
+		"foo",
+		"0:foo",
+		"1.0@",
+		"1.0-1_1",
+	}
+
+	for _, test := range tests {
+		if _, err := ParseVersion(test); err == nil {
+			t.Errorf("ParseVersion(%q) expected error", test)
+		}
+	}
+}
@@ -28,13 +28,14 @@ func (d *Cache) find(id int64) *deb.Package {
 	return debPackage.(*deb.Package)
 }
 
-func (d *Cache) Get(ghPackage github_client.Package) (*deb.Package, error) {
+// Get returns a loaded package, options of its repository are used only on load
+func (d *Cache) Get(ghPackage github_client.Package, options *deb.Options) (*deb.Package, error) {
 	if ghPackage.Asset == nil || ghPackage.Asset.ID == nil {
 		return nil, errors.New("asset is null")
 	}
 
 	deb := d.find(*ghPackage.Asset.ID)
-	return deb, deb.Ensure(ghPackage.Release, ghPackage.Asset)
+	return deb, deb.Ensure(ghPackage.Release, ghPackage.Asset, options)
 }
 
 func (d *Cache) Clear() {
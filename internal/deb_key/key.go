@@ -2,19 +2,40 @@ package deb_key
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"time"
 
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/armor"
 	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 type Key struct {
 	signingKey *openpgp.Entity
 }
 
-func (k *Key) EncodeWithArmor(w io.Writer, body func(w io.Writer) error) error {
+// Fingerprint returns a fingerprint of the signing key
+func (k *Key) Fingerprint() string {
+	return hex.EncodeToString(k.signingKey.PrimaryKey.Fingerprint[:])
+}
+
+// config makes signatures created at signedAt, but not before the key
+func (k *Key) config(signedAt time.Time) *packet.Config {
+	if signedAt.Before(k.signingKey.PrimaryKey.CreationTime) {
+		signedAt = k.signingKey.PrimaryKey.CreationTime
+	}
+
+	return &packet.Config{
+		Time: func() time.Time {
+			return signedAt
+		},
+	}
+}
+
+func (k *Key) EncodeWithArmor(w io.Writer, signedAt time.Time, body func(w io.Writer) error) error {
 	pr, pw := io.Pipe()
 	defer pr.Close()
 
@@ -23,11 +44,11 @@ func (k *Key) EncodeWithArmor(w io.Writer, body func(w io.Writer) error) error {
 		pw.CloseWithError(body(pw))
 	}()
 
-	return openpgp.ArmoredDetachSign(w, k.signingKey, pr, nil)
+	return openpgp.ArmoredDetachSign(w, k.signingKey, pr, k.config(signedAt))
 }
 
-func (k *Key) Encode(w io.Writer, body func(w io.Writer) error) error {
-	wd, err := clearsign.Encode(w, k.signingKey.PrivateKey, nil)
+func (k *Key) Encode(w io.Writer, signedAt time.Time, body func(w io.Writer) error) error {
+	wd, err := clearsign.Encode(w, k.signingKey.PrivateKey, k.config(signedAt))
 	if err != nil {
 		return err
 	}
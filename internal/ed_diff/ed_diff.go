@@ -0,0 +1,189 @@
+package ed_diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrTooManyEdits is returned when files differ more than allowed
+var ErrTooManyEdits = errors.New("too many edits")
+
+// Hunk replaces lines [OldStart, OldEnd) of old file with lines [NewStart, NewEnd) of new file
+type Hunk struct {
+	OldStart, OldEnd int
+	NewStart, NewEnd int
+}
+
+// SplitLines splits text into lines, without the trailing newline
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Diff returns hunks that transform a into b, using the Myers algorithm
+func Diff(a, b []string, maxEdits int) ([]Hunk, error) {
+	// skip common prefix and suffix, as it is the most common case for indices
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	hunks, err := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], maxEdits)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hunks {
+		hunks[i].OldStart += prefix
+		hunks[i].OldEnd += prefix
+		hunks[i].NewStart += prefix
+		hunks[i].NewEnd += prefix
+	}
+	return hunks, nil
+}
+
+func myers(a, b []string, maxEdits int) ([]Hunk, error) {
+	n, m := len(a), len(b)
+	max := n + m
+	if maxEdits > 0 && max > maxEdits {
+		max = maxEdits
+	}
+
+	// at least that many lines have to be added or removed
+	if n-m > max || m-n > max {
+		return nil, ErrTooManyEdits
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// only diagonals -d..d are read in round d, so only those are kept,
+	// which makes the trace grow as O(D^2), not O(D*max)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), nil
+			}
+		}
+	}
+
+	return nil, ErrTooManyEdits
+}
+
+func backtrack(trace [][]int, n, m int) []Hunk {
+	var hunks []Hunk
+	x, y := n, m
+
+	addEdit := func(oldStart, oldEnd, newStart, newEnd int) {
+		// merge with following hunk, as we go backwards
+		if len(hunks) > 0 {
+			last := &hunks[len(hunks)-1]
+			if last.OldStart == oldEnd && last.NewStart == newEnd {
+				last.OldStart = oldStart
+				last.NewStart = newStart
+				return
+			}
+		}
+		hunks = append(hunks, Hunk{oldStart, oldEnd, newStart, newEnd})
+	}
+
+	for d := len(trace) - 1; d > 0; d-- {
+		// window of round d starts at diagonal -d
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+
+		if x == prevX {
+			addEdit(x, x, prevY, y)
+		} else {
+			addEdit(prevX, x, y, y)
+		}
+		x, y = prevX, prevY
+	}
+
+	// hunks were collected from the end
+	for i, j := 0, len(hunks)-1; i < j; i, j = i+1, j-1 {
+		hunks[i], hunks[j] = hunks[j], hunks[i]
+	}
+	return hunks
+}
+
+// WriteEd writes an ed script transforming a into b, in the format of `diff --ed`
+func WriteEd(w io.Writer, a, b []string, maxEdits int) error {
+	hunks, err := Diff(a, b, maxEdits)
+	if err != nil {
+		return err
+	}
+
+	// commands are applied from the end, so line numbers are not affected
+	for i := len(hunks) - 1; i >= 0; i-- {
+		hunk := hunks[i]
+
+		switch {
+		case hunk.NewStart == hunk.NewEnd:
+			fmt.Fprintf(w, "%sd\n", lineRange(hunk.OldStart, hunk.OldEnd))
+			continue
+
+		case hunk.OldStart == hunk.OldEnd:
+			fmt.Fprintf(w, "%da\n", hunk.OldStart)
+
+		default:
+			fmt.Fprintf(w, "%sc\n", lineRange(hunk.OldStart, hunk.OldEnd))
+		}
+
+		for _, line := range b[hunk.NewStart:hunk.NewEnd] {
+			if line == "." {
+				return errors.New("a single dot line cannot be written in ed script")
+			}
+			fmt.Fprintln(w, line)
+		}
+		fmt.Fprintln(w, ".")
+	}
+	return nil
+}
+
+func lineRange(start, end int) string {
+	if end-start == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end)
+}
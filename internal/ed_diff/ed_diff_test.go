@@ -0,0 +1,139 @@
+package ed_diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyEd applies an ed script written by WriteEd, as done by apt's rred
+func applyEd(t *testing.T, lines []string, script string) []string {
+	result := append([]string(nil), lines...)
+	scanner := bufio.NewScanner(strings.NewReader(script))
+
+	for scanner.Scan() {
+		command := scanner.Text()
+		if command == "" {
+			t.Fatalf("empty command")
+		}
+
+		op := command[len(command)-1]
+		parts := strings.SplitN(command[:len(command)-1], ",", 2)
+		start, err := strconv.Atoi(parts[0])
+		if err != nil {
+			t.Fatalf("invalid command %q", command)
+		}
+		end := start
+		if len(parts) == 2 {
+			end, err = strconv.Atoi(parts[1])
+			if err != nil {
+				t.Fatalf("invalid command %q", command)
+			}
+		}
+
+		var added []string
+		if op == 'a' || op == 'c' {
+			for scanner.Scan() && scanner.Text() != "." {
+				added = append(added, scanner.Text())
+			}
+		}
+
+		switch op {
+		case 'a':
+			result = append(result[:start], append(added, result[start:]...)...)
+		case 'c', 'd':
+			result = append(result[:start-1], append(added, result[end:]...)...)
+		default:
+			t.Fatalf("unknown command %q", command)
+		}
+	}
+	return result
+}
+
+func checkPatch(t *testing.T, a, b []string) {
+	var script bytes.Buffer
+	err := WriteEd(&script, a, b, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := applyEd(t, a, script.String())
+	if strings.Join(result, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("patch of %q to %q gives %q:\n%s", a, b, result, script.String())
+	}
+}
+
+func TestWriteEd(t *testing.T) {
+	cases := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\n", "a\nc\n"},
+		{"a\nc\n", "a\nb\nc\n"},
+		{"a\nb\nc\n", "a\nx\nc\n"},
+		{"a\nb\nc\nd\n", "x\nb\ny\nd\nz\n"},
+		{"Package: hello\nVersion: 1.0\n\n", "Package: hello\nVersion: 1.1\n\nPackage: world\nVersion: 2.0\n\n"},
+	}
+
+	for _, c := range cases {
+		checkPatch(t, SplitLines(c.a), SplitLines(c.b))
+	}
+}
+
+func TestWriteEdRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	randomLines := func(lines []string) []string {
+		var result []string
+		for _, line := range lines {
+			switch random.Intn(6) {
+			case 0:
+				// remove
+			case 1:
+				result = append(result, line, fmt.Sprint("added", random.Intn(5)))
+			case 2:
+				result = append(result, fmt.Sprint("changed", random.Intn(5)))
+			default:
+				result = append(result, line)
+			}
+		}
+		return result
+	}
+
+	for i := 0; i < 200; i++ {
+		var a []string
+		for j := random.Intn(50); j > 0; j-- {
+			a = append(a, fmt.Sprint("line", random.Intn(10)))
+		}
+		checkPatch(t, a, randomLines(a))
+	}
+}
+
+func TestWriteEdTooManyEdits(t *testing.T) {
+	a := SplitLines("a\nb\nc\nd\n")
+	b := SplitLines("w\nx\ny\nz\n")
+
+	err := WriteEd(&bytes.Buffer{}, a, b, 4)
+	if err != ErrTooManyEdits {
+		t.Fatalf("expected %v, got %v", ErrTooManyEdits, err)
+	}
+
+	// difference of lengths is already over the limit
+	_, err = Diff(nil, b, 3)
+	if err != ErrTooManyEdits {
+		t.Fatalf("expected %v, got %v", ErrTooManyEdits, err)
+	}
+}
+
+func TestWriteEdDot(t *testing.T) {
+	err := WriteEd(&bytes.Buffer{}, nil, []string{"."}, 0)
+	if err == nil {
+		t.Fatal("expected an error for a single dot line")
+	}
+}
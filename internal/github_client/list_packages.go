@@ -6,11 +6,33 @@ import (
 	"github.com/google/go-github/github"
 )
 
+// PackageSuffixes lists assets that are published in repository
+var PackageSuffixes = []string{".deb", ".ddeb", ".udeb", ".dsc"}
+
+func isPackage(name string) bool {
+	for _, suffix := range PackageSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 type Package struct {
 	Release *github.RepositoryRelease
 	Asset   *github.ReleaseAsset
 }
 
+// RepoName returns a name of repository of release asset
+func (p Package) RepoName() string {
+	// https://github.com/<owner>/<repo>/releases/download/<tag>/<file>
+	parts := strings.Split(p.Asset.GetBrowserDownloadURL(), "/")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
 func (a *API) ListPackages(owner, repo string) ([]Package, error) {
 	releases, _, err := a.ListReleases(owner, repo)
 	if err != nil {
@@ -25,7 +47,7 @@ func (a *API) ListPackages(owner, repo string) ([]Package, error) {
 		}
 
 		for _, asset := range release.Assets {
-			if !strings.HasSuffix(*asset.Name, ".deb") {
+			if !isPackage(*asset.Name) {
 				continue
 			}
 
@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"io"
+	"os/exec"
+)
+
+// Bzip2Writer uses an external bzip2, as Go only implements a decompressor
+func Bzip2Writer(body func(io.Writer) error) func(io.Writer) error {
+	return func(w io.Writer) error {
+		cmd := exec.Command("bzip2", "-c", "-9")
+		cmd.Stdout = w
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			return err
+		}
+
+		err = body(stdin)
+		stdin.Close()
+
+		waitErr := cmd.Wait()
+		if err != nil {
+			return err
+		}
+		return waitErr
+	}
+}
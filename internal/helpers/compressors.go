@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+type Compressor struct {
+	Name        string
+	Extension   string
+	ContentType string
+	Writer      func(body func(io.Writer) error) func(io.Writer) error
+}
+
+var Compressors = []Compressor{
+	{"gz", ".gz", "application/gzip", GzWriter},
+	{"xz", ".xz", "application/x-xz", XzWriter},
+	{"bz2", ".bz2", "application/x-bzip2", Bzip2Writer},
+	{"zstd", ".zst", "application/zstd", ZstdWriter},
+}
+
+// writeAndClose writes body to a compressing writer,
+// the last block is written on close, so its error is returned too
+func writeAndClose(wc io.WriteCloser, body func(io.Writer) error) error {
+	err := body(wc)
+	if err != nil {
+		wc.Close()
+		return err
+	}
+	return wc.Close()
+}
+
+// IsCompressed returns true if file name has extension of any compressor
+func IsCompressed(fileName string) bool {
+	for _, compressor := range Compressors {
+		if strings.HasSuffix(fileName, compressor.Extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindCompressors returns compressors for a list of names, ex. gz,xz,
+// uncompressed indices are always published, so "" alone publishes only them
+func FindCompressors(names []string) (compressors []Compressor, err error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one compressor is required, or \"\" for uncompressed indices only")
+	}
+
+	for idx, name := range names {
+		for _, other := range names[:idx] {
+			if other == name {
+				return nil, fmt.Errorf("duplicate compressor: %q", name)
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, compressor := range Compressors {
+			if compressor.Name == name {
+				compressors = append(compressors, compressor)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown compressor: %q", name)
+		}
+	}
+
+	for _, compressor := range compressors {
+		if compressor.Name != "bz2" {
+			continue
+		}
+		if _, err := exec.LookPath("bzip2"); err != nil {
+			return nil, fmt.Errorf("bz2 compressor requires bzip2: %v", err)
+		}
+	}
+	return
+}
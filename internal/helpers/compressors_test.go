@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCompressors(t *testing.T) {
+	cases := []struct {
+		names    []string
+		expected []string
+		valid    bool
+	}{
+		{[]string{"gz", "xz"}, []string{"gz", "xz"}, true},
+		{[]string{"zstd"}, []string{"zstd"}, true},
+		{[]string{""}, nil, true},
+		{nil, nil, false},
+		{[]string{"gz", "gz"}, nil, false},
+		{[]string{"lzma"}, nil, false},
+	}
+
+	for _, c := range cases {
+		compressors, err := FindCompressors(c.names)
+		if (err == nil) != c.valid {
+			t.Errorf("FindCompressors(%q) error = %v, expected valid = %v", c.names, err, c.valid)
+			continue
+		}
+
+		var names []string
+		for _, compressor := range compressors {
+			names = append(names, compressor.Name)
+		}
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("FindCompressors(%q) = %q, expected %q", c.names, names, c.expected)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package helpers
+
+import (
+	"io"
+
+	"github.com/ulikunitz/xz"
+)
+
+func XzWriter(body func(io.Writer) error) func(io.Writer) error {
+	return func(w io.Writer) error {
+		xz, err := xz.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		return writeAndClose(xz, body)
+	}
+}
@@ -0,0 +1,17 @@
+package helpers
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func ZstdWriter(body func(io.Writer) error) func(io.Writer) error {
+	return func(w io.Writer) error {
+		zst, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		return writeAndClose(zst, body)
+	}
+}
@@ -0,0 +1,32 @@
+package http_helpers
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ayufan/debian-repository/internal/helpers"
+)
+
+// ContentType returns a type of repository file, indices are plain text,
+// files by hash are of any type
+func ContentType(fileName string) string {
+	if strings.Contains("/"+fileName, "/by-hash/") {
+		return "application/octet-stream"
+	}
+
+	for _, compressor := range helpers.Compressors {
+		if strings.HasSuffix(fileName, compressor.Extension) {
+			return compressor.ContentType
+		}
+	}
+
+	switch path.Ext(fileName) {
+	case ".gpg":
+		return "application/pgp-signature"
+	case ".tar":
+		return "application/x-tar"
+	case ".yml":
+		return "application/yaml"
+	}
+	return "text/plain; charset=utf-8"
+}
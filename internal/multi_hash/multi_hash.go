@@ -76,6 +76,14 @@ func (m *MultiHash) hash(hashName string) []byte {
 	return hash.Sum(nil)
 }
 
+func (m *MultiHash) Hex(hashName string) string {
+	return hex.EncodeToString(m.hash(hashName))
+}
+
+func (m *MultiHash) Bytes() []byte {
+	return m.buffer.Bytes()
+}
+
 func (m *MultiHash) WriteReleaseHash(w io.Writer, hashName string, name string) {
 	hash := m.hashes[hashName]
 	checksum := hash.Sum(nil)
@@ -42,3 +42,8 @@ func Write(tag, name string, content []byte) error {
 	os.Remove(cachePath)
 	return os.Rename(f.Name(), cachePath)
 }
+
+func Remove(tag, name string) error {
+	cachePath := filepath.Join(repositoryCache, tag+"."+name)
+	return os.Remove(cachePath)
+}
@@ -0,0 +1,126 @@
+package snapshot_cache
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/groupcache/lru"
+
+	"github.com/ayufan/debian-repository/internal/deb"
+)
+
+// snapshot is a frozen repository built from packages identified by fingerprint
+type snapshot struct {
+	fingerprint string
+	repository  *deb.Repository
+}
+
+func (s *snapshot) valid(fingerprint string) bool {
+	return s != nil && s.fingerprint == fingerprint && !s.repository.Outdated()
+}
+
+type entry struct {
+	current atomic.Pointer[snapshot]
+	build   sync.Mutex
+	size    int64
+}
+
+// Cache keeps the latest snapshot of each (owner, repo, suite, component),
+// a snapshot is swapped only once a new one is built,
+// the oldest ones are evicted when they hold more than maxSize bytes,
+// but never the most recent one
+type Cache struct {
+	cache   *lru.Cache
+	lock    sync.Mutex
+	size    int64
+	maxSize int64
+}
+
+func (c *Cache) find(key string) *entry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	value, found := c.cache.Get(key)
+	if !found {
+		value = &entry{}
+		c.cache.Add(key, value)
+	}
+
+	return value.(*entry)
+}
+
+func (c *Cache) store(key string, e *entry, s *snapshot) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// entry might have been evicted while building
+	if value, found := c.cache.Get(key); !found || value != e {
+		return
+	}
+
+	// the most recent snapshot is always kept, otherwise it would be built on every request
+	size := s.repository.Size()
+	if size > c.maxSize {
+		log.Println("Snapshot of", key, "has", size, "bytes, more than", c.maxSize, "allowed, other snapshots are evicted")
+	}
+
+	e.current.Store(s)
+	c.size += size - e.size
+	e.size = size
+
+	for c.size > c.maxSize && c.cache.Len() > 1 {
+		c.cache.RemoveOldest()
+	}
+}
+
+// Get returns a snapshot matching fingerprint, or builds and freezes a new one,
+// only a single snapshot of key is built at a time
+func (c *Cache) Get(key, fingerprint string, build func() (*deb.Repository, error)) (*deb.Repository, error) {
+	e := c.find(key)
+	if current := e.current.Load(); current.valid(fingerprint) {
+		return current.repository, nil
+	}
+
+	e.build.Lock()
+	defer e.build.Unlock()
+
+	// it might have been built while waiting
+	if current := e.current.Load(); current.valid(fingerprint) {
+		return current.repository, nil
+	}
+
+	repository, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	err = repository.Freeze()
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, e, &snapshot{
+		fingerprint: fingerprint,
+		repository:  repository,
+	})
+	return repository, nil
+}
+
+func (c *Cache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cache.Clear()
+}
+
+func New(itemCount int, maxSize int64) *Cache {
+	c := &Cache{
+		cache:   lru.New(itemCount),
+		maxSize: maxSize,
+	}
+	c.cache.OnEvicted = func(key lru.Key, value interface{}) {
+		c.size -= value.(*entry).size
+	}
+	return c
+}
@@ -0,0 +1,125 @@
+package snapshot_cache
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/ayufan/debian-repository/internal/deb"
+	"github.com/ayufan/debian-repository/internal/deb_key"
+)
+
+func testSigningKey(t *testing.T) *deb_key.Key {
+	config := &packet.Config{RSABits: 1024}
+	entity, err := openpgp.NewEntity("Test", "", "test@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	wr, err := armor.Encode(&buffer, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(wr, config); err != nil {
+		t.Fatal(err)
+	}
+	wr.Close()
+
+	key, err := deb_key.New(buffer.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+type testBuilder struct {
+	key    *deb_key.Key
+	builds int
+}
+
+// build returns an empty flat repository, of the same size for owners of the same length
+func (b *testBuilder) build(owner string) func() (*deb.Repository, error) {
+	return func() (*deb.Repository, error) {
+		b.builds++
+		return deb.NewRepository(owner, "repo", "", "test", "", b.key, nil), nil
+	}
+}
+
+func (b *testBuilder) size(t *testing.T) int64 {
+	repository, _ := b.build("o0")()
+	if err := repository.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+	return repository.Size()
+}
+
+func TestEvictOldest(t *testing.T) {
+	b := &testBuilder{key: testSigningKey(t)}
+	size := b.size(t)
+
+	c := New(100, size*5/2)
+	for _, owner := range []string{"o1", "o2", "o3"} {
+		if _, err := c.Get(owner, "fingerprint", b.build(owner)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, found := c.cache.Get("o1"); found {
+		t.Error("the oldest snapshot is not evicted")
+	}
+	if c.cache.Len() != 2 || c.size > c.maxSize {
+		t.Errorf("%d snapshots of %d bytes are cached, expected 2 of at most %d", c.cache.Len(), c.size, c.maxSize)
+	}
+
+	c.Clear()
+	if c.size != 0 {
+		t.Errorf("size = %d after clear", c.size)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	b := &testBuilder{key: testSigningKey(t)}
+	size := b.size(t)
+	b.builds = 0
+
+	c := New(100, size*10)
+	c.Get("o1", "fingerprint", b.build("o1"))
+	c.Get("o1", "fingerprint", b.build("o1"))
+	if b.builds != 1 {
+		t.Errorf("snapshot is built %d times for the same fingerprint", b.builds)
+	}
+
+	// size of the replaced snapshot is not counted
+	c.Get("o1", "other", b.build("o1"))
+	if b.builds != 2 || c.size > size+size/10 {
+		t.Errorf("snapshot is built %d times, cached %d bytes, expected about %d", b.builds, c.size, size)
+	}
+}
+
+func TestOverSize(t *testing.T) {
+	b := &testBuilder{key: testSigningKey(t)}
+	size := b.size(t)
+	b.builds = 0
+
+	c := New(100, size*3/2)
+	c.Get("o1", "fingerprint", b.build("o1"))
+
+	// a snapshot over the size is kept, and evicts others
+	c.maxSize = size / 2
+	for i := 0; i < 2; i++ {
+		repository, err := c.Get("o2", "fingerprint", b.build("o2"))
+		if err != nil || repository == nil {
+			t.Fatal("snapshot over the size is not served:", err)
+		}
+	}
+	if b.builds != 2 {
+		t.Errorf("snapshot over the size is built %d times, expected once", b.builds-1)
+	}
+	if _, found := c.cache.Get("o1"); found || c.cache.Len() != 1 {
+		t.Errorf("%d snapshots are cached, expected only one over the size", c.cache.Len())
+	}
+}
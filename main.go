@@ -10,10 +10,14 @@ import (
 	"github.com/namsral/flag"
 
 	"github.com/ayufan/debian-repository/internal/apache_log"
+	"github.com/ayufan/debian-repository/internal/by_hash_cache"
+	"github.com/ayufan/debian-repository/internal/config"
 	"github.com/ayufan/debian-repository/internal/deb"
 	"github.com/ayufan/debian-repository/internal/deb_cache"
 	"github.com/ayufan/debian-repository/internal/deb_key"
 	"github.com/ayufan/debian-repository/internal/github_client"
+	"github.com/ayufan/debian-repository/internal/helpers"
+	"github.com/ayufan/debian-repository/internal/snapshot_cache"
 )
 
 var signingKey *deb_key.Key
@@ -27,22 +31,30 @@ func createRoutes() *mux.Router {
 	r.HandleFunc("/orgs/{owner}", indexHandler).Methods("GET")
 	r.HandleFunc("/orgs/{owner}/", indexHandler).Methods("GET")
 	r.HandleFunc("/orgs/{owner}/archive.key", archiveKeyHandler).Methods("GET")
-	r.HandleFunc("/orgs/{owner}/dists/{suite}/{file:.*}", fileHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/changelogs/{component}/{prefix}/{source}/{file}", changelogHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/control/{repo}/{tag_name}/{file_name}", inspectHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/control/{repo}/{tag_name}/{file_name}/", inspectHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/control/{repo}/{tag_name}/{file_name}/{member:.*}", inspectHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/dists/{suite}/{file:.*}", fileHandler).Methods("GET", "HEAD")
 	r.HandleFunc("/orgs/{owner}/{component}", distributionIndexHandler).Methods("GET")
 	r.HandleFunc("/orgs/{owner}/{component}/", distributionIndexHandler).Methods("GET")
 	r.HandleFunc("/orgs/{owner}/{component}/pool/{repo}/{tag_name}/{file_name}", downloadHandler).Methods("GET", "HEAD")
-	r.HandleFunc("/orgs/{owner}/{component}/{file:.*}", fileHandler).Methods("GET")
+	r.HandleFunc("/orgs/{owner}/{component}/{file:.*}", fileHandler).Methods("GET", "HEAD")
 
 	// support dists/
 	r.HandleFunc("/{owner}/{repo}", indexHandler).Methods("GET")
 	r.HandleFunc("/{owner}/{repo}/", indexHandler).Methods("GET")
 	r.HandleFunc("/{owner}/{repo}/archive.key", archiveKeyHandler).Methods("GET")
-	r.HandleFunc("/{owner}/{repo}/dists/{suite}/{file:.*}", fileHandler).Methods("GET")
+	r.HandleFunc("/{owner}/{repo}/changelogs/{component}/{prefix}/{source}/{file}", changelogHandler).Methods("GET")
+	r.HandleFunc("/{owner}/{repo}/dists/{suite}/{file:.*}", fileHandler).Methods("GET", "HEAD")
 	r.HandleFunc("/{owner}/{repo}/pool/{tag_name}/{file_name}", downloadHandler).Methods("GET", "HEAD")
+	r.HandleFunc("/{owner}/{repo}/control/{tag_name}/{file_name}", inspectHandler).Methods("GET")
+	r.HandleFunc("/{owner}/{repo}/control/{tag_name}/{file_name}/", inspectHandler).Methods("GET")
+	r.HandleFunc("/{owner}/{repo}/control/{tag_name}/{file_name}/{member:.*}", inspectHandler).Methods("GET")
 	r.HandleFunc("/{owner}/{repo}/{component}", distributionIndexHandler).Methods("GET")
 	r.HandleFunc("/{owner}/{repo}/{component}/", distributionIndexHandler).Methods("GET")
 	r.HandleFunc("/{owner}/{repo}/{component}/pool/{tag_name}/{file_name}", downloadHandler).Methods("GET", "HEAD")
-	r.HandleFunc("/{owner}/{repo}/{component}/{file:.*}", fileHandler).Methods("GET")
+	r.HandleFunc("/{owner}/{repo}/{component}/{file:.*}", fileHandler).Methods("GET", "HEAD")
 
 	return r
 }
@@ -60,22 +72,48 @@ func main() {
 		}
 
 		log.Println(string(deb.Control))
+		for _, member := range []string{"control.tar", "data.tar"} {
+			compression := deb.Compressions[member]
+			if compression == "" {
+				compression = "none"
+			}
+			log.Println("Compression of", member+":", compression)
+		}
+		log.Println("Files:")
+		for _, fileName := range deb.Files {
+			log.Println("\t" + fileName)
+		}
 		return
 	}
 
 	githubAPI = github_client.New(os.Getenv("GITHUB_TOKEN"), *requestCacheExpiration)
 	packagesCache = deb_cache.New(*packageLruCache)
+	snapshotsCache = snapshot_cache.New(*snapshotLruCache, int64(*snapshotCacheSize)<<20)
+	deb.ByHash = by_hash_cache.New(*byHashRetention, int64(*byHashCacheSize)<<20)
+	deb.PdiffHistory = *pdiffHistory
 
 	signingKey, err = deb_key.New(os.Getenv("GPG_KEY"))
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	if *configFile != "" {
+		repositoryConfig, err = config.Load(*configFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Using config:", *configFile)
+	}
+
+	if *baseURL == "" {
+		log.Println("Changelogs are not advertised in Release, set -baseURL to enable them")
+	}
+
 	deb.Suites = strings.Split(*suites, ",")
 	if len(deb.Suites) == 0 {
-		log.Println("Allowed suites: none")
+		log.Println("Default suites: none")
 	} else {
-		log.Println("Allowed suites:", strings.Join(deb.Suites, ", "))
+		log.Println("Default suites:", strings.Join(deb.Suites, ", "))
 	}
 
 	deb.Architectures = strings.Split(*architectures, ",")
@@ -85,6 +123,21 @@ func main() {
 		log.Println("Default architectures:", strings.Join(deb.Architectures, ", "))
 	}
 
+	deb.Compressors, err = helpers.FindCompressors(strings.Split(*compressors, ","))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Compressors:", *compressors)
+
+	if *disableLintRules != "" {
+		deb.DisabledLintRules = strings.Split(*disableLintRules, ",")
+		err = deb.ValidateLintRules(deb.DisabledLintRules)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Disabled lint rules:", strings.Join(deb.DisabledLintRules, ", "))
+	}
+
 	allowedOwners = strings.Split(os.Getenv("ALLOWED_ORGS"), ",")
 	if len(allowedOwners) == 0 {
 		log.Println("Allowed owners: none")
@@ -1,19 +1,27 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/mux"
 
+	"github.com/ayufan/debian-repository/internal/config"
 	"github.com/ayufan/debian-repository/internal/deb"
 	"github.com/ayufan/debian-repository/internal/deb_cache"
 	"github.com/ayufan/debian-repository/internal/github_client"
+	"github.com/ayufan/debian-repository/internal/snapshot_cache"
 )
 
 var allowedOwners []string
 var githubAPI *github_client.API
 var packagesCache *deb_cache.Cache
+var snapshotsCache *snapshot_cache.Cache
+var repositoryConfig *config.Config
 
 func isOwnerAllowed(owner string) bool {
 	for _, allowedOwner := range allowedOwners {
@@ -24,6 +32,12 @@ func isOwnerAllowed(owner string) bool {
 	return false
 }
 
+// getPackage returns a package loaded with options of its own repository
+func getPackage(owner string, ghPackage github_client.Package) (*deb.Package, error) {
+	options := repositoryConfig.Options(owner, ghPackage.RepoName())
+	return packagesCache.Get(ghPackage, options)
+}
+
 func enumeratePackages(w http.ResponseWriter, r *http.Request, fn func(ghPackage github_client.Package) error) error {
 	vars := mux.Vars(r)
 
@@ -42,7 +56,7 @@ func enumeratePackages(w http.ResponseWriter, r *http.Request, fn func(ghPackage
 	for i := 0; i < 4; i++ {
 		go func() {
 			for ghPackage := range ch {
-				packagesCache.Get(ghPackage)
+				getPackage(vars["owner"], ghPackage)
 			}
 		}()
 	}
@@ -63,15 +77,38 @@ func enumeratePackages(w http.ResponseWriter, r *http.Request, fn func(ghPackage
 	return nil
 }
 
+func getBaseURL(r *http.Request) string {
+	schema := r.Header.Get("X-Forwarded-Proto")
+	if schema == "" {
+		schema = "http"
+	}
+	return schema + "://" + r.Host
+}
+
+// getRepositoryURL returns a URL of repository used in Release,
+// it is configured, as Release is signed and has to be the same for all clients
+func getRepositoryURL(r *http.Request) string {
+	vars := mux.Vars(r)
+
+	if *baseURL == "" {
+		return ""
+	} else if vars["repo"] == "" {
+		return strings.TrimSuffix(*baseURL, "/") + "/orgs/" + vars["owner"]
+	}
+	return strings.TrimSuffix(*baseURL, "/") + "/" + vars["owner"] + "/" + vars["repo"]
+}
+
 func getRepository(w http.ResponseWriter, r *http.Request) (*deb.Repository, error) {
 	vars := mux.Vars(r)
 
+	options := repositoryConfig.Options(vars["owner"], vars["repo"])
+
 	repository := deb.NewRepository(vars["owner"], vars["repo"],
 		vars["suite"], vars["component"],
-		signingKey)
+		getRepositoryURL(r), signingKey, options)
 
 	err := enumeratePackages(w, r, func(ghPackage github_client.Package) error {
-		deb, err := packagesCache.Get(ghPackage)
+		deb, err := getPackage(vars["owner"], ghPackage)
 		if err == nil {
 			repository.Add(deb)
 		}
@@ -79,6 +116,46 @@ func getRepository(w http.ResponseWriter, r *http.Request) (*deb.Repository, err
 	})
 
 	repository.Sort()
+	repository.Prune()
 
 	return repository, err
 }
+
+// getFingerprint identifies packages of repository and their status,
+// a snapshot is rebuilt when it changes
+func getFingerprint(w http.ResponseWriter, r *http.Request) (string, error) {
+	vars := mux.Vars(r)
+
+	var lines []string
+
+	err := enumeratePackages(w, r, func(ghPackage github_client.Package) error {
+		_, err := getPackage(vars["owner"], ghPackage)
+		lines = append(lines, fmt.Sprintln(ghPackage.Asset.GetID(), ghPackage.Asset.GetUpdatedAt(),
+			ghPackage.Release.GetTagName(), ghPackage.Release.GetPrerelease(), err == nil))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(lines)
+
+	hash := sha256.Sum256([]byte(strings.Join(lines, "")))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getSnapshot returns a frozen repository, that is rebuilt only when packages change
+func getSnapshot(w http.ResponseWriter, r *http.Request) (*deb.Repository, error) {
+	vars := mux.Vars(r)
+
+	fingerprint, err := getFingerprint(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	key := strings.Join([]string{vars["owner"], vars["repo"], vars["suite"], vars["component"]}, "/")
+
+	return snapshotsCache.Get(key, fingerprint, func() (*deb.Repository, error) {
+		return getRepository(w, r)
+	})
+}